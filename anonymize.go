@@ -0,0 +1,160 @@
+package import_export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+)
+
+const (
+	AnonymizeFakeEmail  = "fake_email"
+	AnonymizeHash       = "hash"
+	AnonymizeKeepDomain = "keep_domain"
+	AnonymizeNull       = "null"
+	AnonymizeRedact     = "redact"
+	AnonymizeShuffle    = "shuffle"
+)
+
+var anonymizeStrategies = []any{
+	AnonymizeFakeEmail,
+	AnonymizeHash,
+	AnonymizeKeepDomain,
+	AnonymizeNull,
+	AnonymizeRedact,
+	AnonymizeShuffle,
+}
+
+// validateAnonymizeRules validates the anonymize config keys are in the
+// "collection.field" format and that every strategy is known.
+func validateAnonymizeRules(value any) error {
+	rules, _ := value.(map[string]string)
+	for key, strategy := range rules {
+		collectionName, fieldName, ok := strings.Cut(key, ".")
+		if !ok || collectionName == "" || fieldName == "" {
+			return validation.NewError(
+				"validation_invalid_anonymize_key",
+				fmt.Sprintf("Invalid key %q, must be in the format collection.field", key),
+			)
+		}
+		if err := validation.Validate(strategy, validation.In(anonymizeStrategies...)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// anonymizeFieldTypes lists the field types each strategy applies to. The
+// other strategies apply to all field types.
+var anonymizeFieldTypes = map[string][]string{
+	AnonymizeFakeEmail:  {core.FieldTypeEmail, core.FieldTypeText},
+	AnonymizeHash:       {core.FieldTypeText, core.FieldTypeEditor},
+	AnonymizeKeepDomain: {core.FieldTypeEmail, core.FieldTypeText},
+	AnonymizeRedact:     {core.FieldTypeText, core.FieldTypeEditor},
+}
+
+// checkAnonymizeRules checks that the fields of the anonymize rules for the
+// collections exist and that their strategies support the field types, so
+// an export fails before writing any file.
+func (p *Plugin) checkAnonymizeRules(collections []*core.Collection) error {
+	keys := slices.Sorted(maps.Keys(p.Anonymize))
+	for _, key := range keys {
+		strategy := p.Anonymize[key]
+		collectionName, fieldName, _ := strings.Cut(key, ".")
+		i := slices.IndexFunc(collections, func(c *core.Collection) bool {
+			return c.Name == collectionName
+		})
+		if i < 0 {
+			continue
+		}
+		field := collections[i].Fields.GetByName(fieldName)
+		if field == nil {
+			return fmt.Errorf("anonymize rule %q: field does not exist", key)
+		}
+		if fieldTypes, ok := anonymizeFieldTypes[strategy]; ok && !slices.Contains(fieldTypes, field.Type()) {
+			return fmt.Errorf(
+				"anonymize rule %q: %s does not support %s fields, only %s",
+				key,
+				strategy,
+				field.Type(),
+				strings.Join(fieldTypes, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// anonymizeRecords applies the configured anonymize rules for the
+// collection to the records in place. The shuffle strategy shuffles the
+// values among the given records only, i.e. within a file of an export.
+func (p *Plugin) anonymizeRecords(collection *core.Collection, records []*core.Record) error {
+	if err := p.checkAnonymizeRules([]*core.Collection{collection}); err != nil {
+		return err
+	}
+
+	for key, strategy := range p.Anonymize {
+		collectionName, fieldName, _ := strings.Cut(key, ".")
+		if collectionName != collection.Name {
+			continue
+		}
+
+		switch strategy {
+		case AnonymizeShuffle:
+			values := make([]any, len(records))
+			for i, record := range records {
+				values[i] = record.Get(fieldName)
+			}
+			rand.Shuffle(len(values), func(i, j int) {
+				values[i], values[j] = values[j], values[i]
+			})
+			for i, record := range records {
+				record.Set(fieldName, values[i])
+			}
+			continue
+		case AnonymizeNull:
+			// also clears multi-valued fields
+			for _, record := range records {
+				record.Set(fieldName, nil)
+			}
+			continue
+		}
+
+		for _, record := range records {
+			value := cast.ToString(record.Get(fieldName))
+			if value == "" {
+				continue // keep empty values empty
+			}
+			switch strategy {
+			case AnonymizeFakeEmail:
+				record.Set(fieldName, fmt.Sprintf("user_%s@example.com", p.anonymizeHash(value)[:16]))
+			case AnonymizeHash:
+				record.Set(fieldName, p.anonymizeHash(value))
+			case AnonymizeKeepDomain:
+				domain := "example.com"
+				if i := strings.LastIndex(value, "@"); i >= 0 {
+					domain = value[i+1:]
+				}
+				record.Set(fieldName, fmt.Sprintf("%s@%s", p.anonymizeHash(value)[:16], domain))
+			case AnonymizeRedact:
+				record.Set(fieldName, "REDACTED")
+			}
+		}
+	}
+	return nil
+}
+
+// anonymizeHash returns a deterministic hex encoded hash of the value so
+// that the same input maps to the same output across exports.
+func (p *Plugin) anonymizeHash(value string) string {
+	mac := hmac.New(sha256.New, []byte(p.AnonymizeSalt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,167 @@
+package import_export
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func testAnonymizeCollection() *core.Collection {
+	collection := core.NewBaseCollection("people")
+	collection.Fields.Add(
+		&core.EmailField{Name: "email"},
+		&core.TextField{Name: "name"},
+		&core.EditorField{Name: "bio"},
+		&core.SelectField{Name: "tags", MaxSelect: 3, Values: []string{"a", "b", "c"}},
+		&core.NumberField{Name: "score"},
+	)
+	return collection
+}
+
+func TestCheckAnonymizeRules(t *testing.T) {
+	scenarios := []struct {
+		strategy      string
+		field         string
+		expectedError string
+	}{
+		{AnonymizeFakeEmail, "email", ""},
+		{AnonymizeFakeEmail, "name", ""},
+		{AnonymizeFakeEmail, "tags", "fake_email does not support select fields"},
+		{AnonymizeKeepDomain, "email", ""},
+		{AnonymizeKeepDomain, "score", "keep_domain does not support number fields"},
+		{AnonymizeHash, "name", ""},
+		{AnonymizeHash, "bio", ""},
+		{AnonymizeHash, "email", "hash does not support email fields"},
+		{AnonymizeHash, "score", "hash does not support number fields"},
+		{AnonymizeRedact, "bio", ""},
+		{AnonymizeRedact, "tags", "redact does not support select fields"},
+		{AnonymizeNull, "tags", ""},
+		{AnonymizeNull, "score", ""},
+		{AnonymizeShuffle, "tags", ""},
+		{AnonymizeShuffle, "missing", "field does not exist"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.strategy+" "+s.field, func(t *testing.T) {
+			p := &Plugin{Anonymize: map[string]string{
+				"people." + s.field: s.strategy,
+				// rules of other collections are ignored
+				"posts.title": AnonymizeHash,
+			}}
+			err := p.checkAnonymizeRules([]*core.Collection{testAnonymizeCollection()})
+			if s.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), s.expectedError) {
+				t.Fatalf("Expected error %q, got %v", s.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAnonymizeRecords(t *testing.T) {
+	hex16 := "[0-9a-f]{16}"
+
+	scenarios := []struct {
+		strategy string
+		field    string
+		// expected matches the anonymized non-empty value
+		expected *regexp.Regexp
+	}{
+		{AnonymizeFakeEmail, "email", regexp.MustCompile("^user_" + hex16 + "@example.com$")},
+		{AnonymizeKeepDomain, "email", regexp.MustCompile("^" + hex16 + "@example.org$")},
+		{AnonymizeHash, "name", regexp.MustCompile("^[0-9a-f]{64}$")},
+		{AnonymizeRedact, "bio", regexp.MustCompile("^REDACTED$")},
+		{AnonymizeNull, "name", regexp.MustCompile("^$")},
+		{AnonymizeNull, "tags", regexp.MustCompile(`^\[\]$`)},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.strategy+" "+s.field, func(t *testing.T) {
+			collection := testAnonymizeCollection()
+			p := &Plugin{AnonymizeSalt: "salt", Anonymize: map[string]string{"people." + s.field: s.strategy}}
+
+			newRecords := func() []*core.Record {
+				alice := core.NewRecord(collection)
+				alice.Load(map[string]any{
+					"email": "alice@example.org",
+					"name":  "Alice",
+					"bio":   "<p>Hi</p>",
+					"tags":  []string{"a", "b"},
+				})
+				return []*core.Record{alice, core.NewRecord(collection)}
+			}
+
+			records := newRecords()
+			if err := p.anonymizeRecords(collection, records); err != nil {
+				t.Fatal(err)
+			}
+			value := records[0].GetString(s.field)
+			if s.field == "tags" {
+				value = "[" + strings.Join(records[0].GetStringSlice(s.field), ",") + "]"
+			}
+			if !s.expected.MatchString(value) {
+				t.Errorf("Expected a value matching %s, got %q", s.expected, value)
+			}
+			if s.field != "tags" {
+				if empty := records[1].GetString(s.field); empty != "" {
+					t.Errorf("Expected the empty value to stay empty, got %q", empty)
+				}
+			}
+
+			// deterministic across exports
+			again := newRecords()
+			if err := p.anonymizeRecords(collection, again); err != nil {
+				t.Fatal(err)
+			}
+			if again[0].GetString(s.field) != records[0].GetString(s.field) {
+				t.Errorf("Expected the same output for the same value, got %q and %q", records[0].GetString(s.field), again[0].GetString(s.field))
+			}
+		})
+	}
+
+	t.Run("shuffle", func(t *testing.T) {
+		collection := testAnonymizeCollection()
+		p := &Plugin{Anonymize: map[string]string{"people.tags": AnonymizeShuffle}}
+
+		records := []*core.Record{}
+		expected := []string{}
+		for _, tags := range [][]string{{"a"}, {"a", "b"}, {"b", "c"}, {}, {"c"}} {
+			record := core.NewRecord(collection)
+			record.Set("tags", tags)
+			records = append(records, record)
+			expected = append(expected, strings.Join(tags, ","))
+		}
+
+		if err := p.anonymizeRecords(collection, records); err != nil {
+			t.Fatal(err)
+		}
+
+		// the multi-valued values are moved between records as a whole
+		values := []string{}
+		for _, record := range records {
+			values = append(values, strings.Join(record.GetStringSlice("tags"), ","))
+		}
+		slices.Sort(values)
+		slices.Sort(expected)
+		if !slices.Equal(values, expected) {
+			t.Errorf("Expected the shuffled values %q, got %q", expected, values)
+		}
+	})
+
+	t.Run("unsupported field type", func(t *testing.T) {
+		collection := testAnonymizeCollection()
+		p := &Plugin{Anonymize: map[string]string{"people.tags": AnonymizeRedact}}
+		record := core.NewRecord(collection)
+		record.Set("tags", []string{"a"})
+		if err := p.anonymizeRecords(collection, []*core.Record{record}); err == nil {
+			t.Fatal("Expected an unsupported field type error, got nil")
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pocketbase/pocketbase/tools/inflector"
+	"github.com/spf13/cobra"
 )
 
 // borrowed from pocketbase to support older pocketbase versions
@@ -39,6 +40,18 @@ func confirm(message string, fallback bool) bool {
 	}
 }
 
+// stdioArgs validates the optional "-" argument, used to read records from
+// stdin or write them to stdout instead of the records directory.
+func stdioArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+		return err
+	}
+	if len(args) == 1 && args[0] != "-" {
+		return fmt.Errorf("invalid argument %q, only \"-\" is supported", args[0])
+	}
+	return nil
+}
+
 func backupName(name string) string {
 	return fmt.Sprintf(
 		"%s_%s.zip",
@@ -0,0 +1,300 @@
+package import_export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+)
+
+// Keys of the collections that change without a schema change.
+var collectionDiffIgnoredKeys = []string{"created", "updated"}
+
+func (p *Plugin) DiffCollectionsCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "collections",
+		Short:   "compare the collection files of the collections directory with the database",
+		Long:    "compare the collection files of the collections directory with the database, exiting with status 1 if they differ",
+		Aliases: []string{"collection", "col", "c"},
+		Args:    cobra.ExactArgs(0),
+	}
+
+	cmd.Flags().StringVar(&p.CollectionsDir, "collections_dir", p.CollectionsDir, "Path to directory for collections schema json files")
+
+	for _, opt := range p.CollectionsEncoding.Options() {
+		cmd.Flags().VarPF(p.CollectionsEncoding, opt, "", fmt.Sprintf("%s encoding", opt)).NoOptDefVal = opt
+	}
+	cmd.MarkFlagsMutuallyExclusive(p.CollectionsEncoding.Options()...)
+
+	collectionNames := []string{}
+	cmd.Flags().StringSliceVar(&collectionNames, "collection", collectionNames, "Collections to include in the diff, otherwise compares all")
+
+	p.registerScopeFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// validate manually to catch changes to config due to cli flags
+		if err := p.Validate(); err != nil {
+			return err
+		}
+
+		decoder, ok := handlers[p.CollectionsEncoding.String()].(CollectionHandler)
+		if !ok {
+			return ErrNoCollectionHandler
+		}
+
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.CollectionsEncoding)
+
+		included := func(name string, system bool) bool {
+			if len(collectionNames) > 0 {
+				return slices.Contains(collectionNames, name)
+			}
+			return p.System || !system
+		}
+
+		fileCollections, err := readCollectionFiles(p.CollectionsDir, decoder)
+		if err != nil {
+			return err
+		}
+		// compare portable exports by the ids they resolve to on import,
+		// reporting relations to unknown collections as differences
+		unknown := map[string][]string{}
+		if err := resolvePortable(app, fileCollections); err != nil {
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				return err
+			}
+			for _, err := range joined.Unwrap() {
+				var unknownErr *unknownCollectionError
+				if !errors.As(err, &unknownErr) {
+					return err
+				}
+				unknown[unknownErr.collection] = append(
+					unknown[unknownErr.collection],
+					fmt.Sprintf("! fields.%s.collectionId: unknown collection %q", unknownErr.field, unknownErr.target),
+				)
+			}
+		}
+		// secrets are not compared, so unset variables only result in a warning
+		if err := expandEnv(fileCollections); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		files := map[string]map[string]any{}
+		for _, collection := range fileCollections {
+			collection, err := p.normalizeCollection(collection)
+			if err != nil {
+				return err
+			}
+			name := cast.ToString(collection["name"])
+			if included(name, cast.ToBool(collection["system"])) {
+				files[name] = collection
+			}
+		}
+
+		dbCollections := []*core.Collection{}
+		if err := app.CollectionQuery().All(&dbCollections); err != nil {
+			return err
+		}
+		db := map[string]map[string]any{}
+		for _, collection := range dbCollections {
+			if !included(collection.Name, collection.System) {
+				continue
+			}
+			if db[collection.Name], err = p.normalizeCollection(collection); err != nil {
+				return err
+			}
+		}
+
+		names := slices.Sorted(maps.Keys(files))
+		for name := range db {
+			if files[name] == nil {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+
+		differ := 0
+		fmt.Printf("--- %s\n+++ database\n", p.CollectionsDir)
+		for _, name := range names {
+			file, dbCollection := files[name], db[name]
+			switch {
+			case dbCollection == nil:
+				fmt.Printf("- collection %s (missing from the database)\n", name)
+				for _, line := range unknown[name] {
+					fmt.Printf("    %s\n", line)
+				}
+			case file == nil:
+				fmt.Printf("+ collection %s (missing from the files)\n", name)
+			default:
+				lines := slices.Concat(unknown[name], diffCollection(file, dbCollection))
+				if len(lines) == 0 {
+					continue
+				}
+				fmt.Printf("~ collection %s\n", name)
+				for _, line := range lines {
+					fmt.Printf("    %s\n", line)
+				}
+			}
+			differ++
+		}
+
+		if differ == 0 {
+			fmt.Fprintln(os.Stderr, "The collection files match the database.")
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "%d collection(s) differ from the database.\n", differ)
+		return errDiffFound
+	}
+
+	return cmd
+}
+
+// normalizeCollection converts a collection, decoded or from the database,
+// to its generic json representation without the keys that do not take
+// part in the diff. Decoded collections are loaded into a collection model
+// first, as on import.
+func (p *Plugin) normalizeCollection(collection any) (map[string]any, error) {
+	raw, err := json.Marshal(collection)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := collection.(map[string]any); ok {
+		// fill in the defaults of the keys missing from the file
+		decoded := &core.Collection{}
+		if err := json.Unmarshal(raw, decoded); err != nil {
+			return nil, err
+		}
+		if raw, err = json.Marshal(decoded); err != nil {
+			return nil, err
+		}
+	}
+	result := map[string]any{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	for _, key := range collectionDiffIgnoredKeys {
+		delete(result, key)
+	}
+	if !p.IncludeOauth2 {
+		delete(result, "oauth2") // not exported by default
+	}
+	return result, nil
+}
+
+// diffCollection returns the differences of the normalized collections,
+// with the fields compared by name and the indexes as a set.
+func diffCollection(file, db map[string]any) []string {
+	lines := []string{}
+
+	fileFields, dbFields := fieldsByName(file["fields"]), fieldsByName(db["fields"])
+	fieldNames := slices.Sorted(maps.Keys(fileFields))
+	for name := range dbFields {
+		if fileFields[name] == nil {
+			fieldNames = append(fieldNames, name)
+		}
+	}
+	slices.Sort(fieldNames)
+	for _, name := range fieldNames {
+		path := "fields." + name
+		switch {
+		case dbFields[name] == nil:
+			lines = append(lines, fmt.Sprintf("- %s: %s", path, diffJSON(fileFields[name])))
+		case fileFields[name] == nil:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", path, diffJSON(dbFields[name])))
+		default:
+			lines = diffValues(lines, path, fileFields[name], dbFields[name])
+		}
+	}
+	if fileOrder, dbOrder := fieldOrder(file["fields"]), fieldOrder(db["fields"]); len(lines) == 0 && !slices.Equal(fileOrder, dbOrder) {
+		lines = append(lines, fmt.Sprintf("~ fields order: %s -> %s", diffJSON(fileOrder), diffJSON(dbOrder)))
+	}
+
+	fileIndexes, dbIndexes := cast.ToStringSlice(file["indexes"]), cast.ToStringSlice(db["indexes"])
+	for _, index := range fileIndexes {
+		if !slices.Contains(dbIndexes, index) {
+			lines = append(lines, "- indexes: "+index)
+		}
+	}
+	for _, index := range dbIndexes {
+		if !slices.Contains(fileIndexes, index) {
+			lines = append(lines, "+ indexes: "+index)
+		}
+	}
+
+	rest := func(m map[string]any) map[string]any {
+		m = maps.Clone(m)
+		delete(m, "fields")
+		delete(m, "indexes")
+		return m
+	}
+	return diffValues(lines, "", rest(file), rest(db))
+}
+
+// diffValues appends the differences between the json values a and b at
+// path to lines, recursing into objects. Missing keys equal null values,
+// since not all encodings can represent null.
+func diffValues(lines []string, path string, a, b any) []string {
+	aMap, aOk := a.(map[string]any)
+	bMap, bOk := b.(map[string]any)
+	if aOk && bOk {
+		keys := slices.Sorted(maps.Keys(aMap))
+		for key := range bMap {
+			if _, ok := aMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			lines = diffValues(lines, keyPath, aMap[key], bMap[key])
+		}
+		return lines
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", path, diffJSON(a), diffJSON(b)))
+	}
+	return lines
+}
+
+// fieldsByName returns the fields of the normalized collection keyed by name.
+func fieldsByName(fields any) map[string]any {
+	result := map[string]any{}
+	for _, field := range cast.ToSlice(fields) {
+		if field, ok := field.(map[string]any); ok {
+			result[cast.ToString(field["name"])] = field
+		}
+	}
+	return result
+}
+
+// fieldOrder returns the names of the fields of the normalized collection.
+func fieldOrder(fields any) []string {
+	names := []string{}
+	for _, field := range cast.ToSlice(fields) {
+		if field, ok := field.(map[string]any); ok {
+			names = append(names, cast.ToString(field["name"]))
+		}
+	}
+	return names
+}
+
+// diffJSON formats a value of a diff as compact json.
+func diffJSON(value any) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,88 @@
+package import_export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestDiffCollection(t *testing.T) {
+	title := func(max int) *core.TextField {
+		return &core.TextField{Id: "text_title", Name: "title", Max: max}
+	}
+	body := &core.EditorField{Id: "editor_body", Name: "body"}
+	summary := &core.TextField{Id: "text_summary", Name: "summary"}
+
+	scenarios := []struct {
+		name string
+		file func(c *core.Collection)
+		db   func(c *core.Collection)
+		// expected holds the prefixes of the expected lines
+		expected []string
+	}{
+		{
+			"equal",
+			func(c *core.Collection) { c.Fields.Add(title(100), body) },
+			func(c *core.Collection) { c.Fields.Add(title(100), body) },
+			[]string{},
+		},
+		{
+			"added, removed and changed fields",
+			func(c *core.Collection) {
+				c.Fields.Add(title(50), summary)
+				c.AddIndex("idx_title", false, "title", "")
+				c.ListRule = types.Pointer("")
+			},
+			func(c *core.Collection) {
+				c.Fields.Add(title(100), body)
+			},
+			[]string{
+				`+ fields.body: {"convertURLs":false,"hidden":false,"id":"editor_body",`,
+				`- fields.summary: {"autogeneratePattern":"","hidden":false,"id":"text_summary",`,
+				"~ fields.title.max: 50 -> 100",
+				"- indexes: CREATE INDEX `idx_title` ON `posts` (title)",
+				`~ listRule: "" -> null`,
+			},
+		},
+		{
+			"field order",
+			func(c *core.Collection) { c.Fields.Add(title(100), body) },
+			func(c *core.Collection) { c.Fields.Add(body, title(100)) },
+			[]string{`~ fields order: ["id","title","body"] -> ["id","body","title"]`},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			p := &Plugin{}
+
+			newCollection := func(setup func(c *core.Collection)) *core.Collection {
+				c := core.NewBaseCollection("posts")
+				c.Id = "pbc_posts"
+				setup(c)
+				return c
+			}
+
+			file, err := p.normalizeCollection(newCollection(s.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			db, err := p.normalizeCollection(newCollection(s.db))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := diffCollection(file, db)
+			if len(lines) != len(s.expected) {
+				t.Fatalf("Expected %d lines, got %d:\n%s", len(s.expected), len(lines), strings.Join(lines, "\n"))
+			}
+			for i, line := range lines {
+				if !strings.HasPrefix(line, s.expected[i]) {
+					t.Errorf("Expected line %d to start with %q, got %q", i, s.expected[i], line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package import_export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbuilds/import_export/flags"
+	"github.com/spf13/cobra"
+)
+
+const (
+	diffFormatText = "text"
+	diffFormatJSON = "json"
+)
+
+// recordsDiff holds the differences between the records files of a
+// collection and its records in the database, from the files to the
+// database, as with the collection diffs.
+type recordsDiff struct {
+	Collection     string              `json:"collection"`
+	OnlyInFiles    []map[string]any    `json:"only_in_files"`
+	OnlyInDatabase []map[string]any    `json:"only_in_database"`
+	Changed        []*recordDiffChange `json:"changed"`
+}
+
+// recordDiffChange holds the changed field values of a record.
+type recordDiffChange struct {
+	Id     string                      `json:"id"`
+	Fields map[string]*recordDiffValue `json:"fields"`
+}
+
+type recordDiffValue struct {
+	File     any `json:"file"`
+	Database any `json:"database"`
+}
+
+// fileRecord is a record of the records files along with the columns of
+// its rows, which are the only fields compared, since exports may leave out
+// fields, e.g. hidden fields. columns is nil if the handler can't decode
+// rows, in which case all fields are compared.
+type fileRecord struct {
+	record  *core.Record
+	columns []string
+}
+
+// fields returns the fields that are among the columns of the record.
+func (f *fileRecord) fields(fields []string) []string {
+	if f.columns == nil {
+		return fields
+	}
+	return slices.DeleteFunc(slices.Clone(fields), func(name string) bool {
+		return !slices.Contains(f.columns, name)
+	})
+}
+
+func (p *Plugin) DiffRecordsCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "records",
+		Short:   "compare the records files of a collection with the database",
+		Long:    "compare the records files of a collection, or stdin with \"-\", with the database by id, exiting with status 1 if they differ",
+		Aliases: []string{"record", "rec", "r"},
+		Args:    stdioArgs,
+	}
+
+	cmd.Flags().StringVar(&p.RecordsDir, "records_dir", p.RecordsDir, "Path to directory for records csv files")
+
+	for _, opt := range p.RecordsEncoding.Options() {
+		cmd.Flags().VarPF(p.RecordsEncoding, opt, "", fmt.Sprintf("%s encoding", opt)).NoOptDefVal = opt
+	}
+	cmd.MarkFlagsMutuallyExclusive(p.RecordsEncoding.Options()...)
+
+	var collectionName string
+	cmd.Flags().StringVar(&collectionName, "collection", collectionName, "Collection to compare")
+	cmd.MarkFlagRequired("collection")
+
+	format := flags.NewRadioValue(diffFormatText, diffFormatJSON)
+	cmd.Flags().Var(format, "format", "Output format: text or json")
+
+	p.registerScopeFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// validate manually to catch changes to config due to cli flags
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if err := format.Validate(); err != nil {
+			return fmt.Errorf("format: %w", err)
+		}
+
+		decoder, ok := handlers[p.RecordsEncoding.String()].(RecordsHandler)
+		if !ok {
+			return ErrNoRecordsHandler
+		}
+
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.RecordsEncoding)
+
+		collection, err := app.FindCollectionByNameOrId(collectionName)
+		if err != nil {
+			return fmt.Errorf("collection %s: %w", collectionName, err)
+		}
+		if !p.includesRecordsOf(collection) {
+			return fmt.Errorf(
+				"the records of system collection %s are excluded, use --system (and --include_oauth2 for oauth2 account links) to compare them",
+				collection.Name,
+			)
+		}
+
+		paths := []string{"-"}
+		source := "stdin"
+		if len(args) == 0 {
+			skip, err := p.allProfileDirs()
+			if err != nil {
+				return err
+			}
+			files, err := findRecordsFiles(p.RecordsDir, decoder.FileExtension(), skip)
+			if err != nil {
+				return err
+			}
+			paths = nil
+			for _, f := range files {
+				if f.Collection == collection.Name {
+					paths = append(paths, f.Path)
+				}
+			}
+			if len(paths) == 0 {
+				return fmt.Errorf("no records files of collection %s in %q", collection.Name, p.RecordsDir)
+			}
+			source = strings.Join(paths, ", ")
+		}
+
+		fileRecords, noId, err := readRecordsFiles(decoder, collection, paths)
+		if err != nil {
+			return err
+		}
+
+		dbRecords, err := app.FindAllRecords(collection)
+		if err != nil {
+			return err
+		}
+
+		diff, err := diffRecords(collection, fileRecords, noId, dbRecords)
+		if err != nil {
+			return err
+		}
+
+		if format.String() == diffFormatJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(diff); err != nil {
+				return err
+			}
+		} else {
+			diff.writeText(os.Stdout, source)
+		}
+
+		if len(diff.OnlyInFiles)+len(diff.OnlyInDatabase)+len(diff.Changed) == 0 {
+			fmt.Fprintf(os.Stderr, "The records files of collection %s match the database.\n", collection.Name)
+			return nil
+		}
+
+		fmt.Fprintf(
+			os.Stderr,
+			"%d records only in the files, %d only in the database and %d changed in collection %s.\n",
+			len(diff.OnlyInFiles),
+			len(diff.OnlyInDatabase),
+			len(diff.Changed),
+			collection.Name,
+		)
+		return errDiffFound
+	}
+
+	return cmd
+}
+
+// readRecordsFiles decodes the records files in order, keying the records
+// by id. Later files, i.e. deltas, update the records of the earlier files.
+func readRecordsFiles(
+	decoder RecordsHandler,
+	collection *core.Collection,
+	paths []string,
+) (map[string]*fileRecord, []*fileRecord, error) {
+	fileRecords := map[string]*fileRecord{}
+	noId := []*fileRecord{}
+	for _, path := range paths {
+		records, err := decodeRecordsFile(decoder, collection, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, f := range records {
+			id := f.record.Id
+			previous := fileRecords[id]
+			switch {
+			case id == "":
+				noId = append(noId, f)
+			case previous != nil && previous.columns != nil && f.columns != nil:
+				// as on import, only the columns of the delta change
+				for _, column := range f.columns {
+					previous.record.SetRaw(column, f.record.GetRaw(column))
+					if !slices.Contains(previous.columns, column) {
+						previous.columns = append(previous.columns, column)
+					}
+				}
+			default:
+				fileRecords[id] = f
+			}
+		}
+	}
+	return fileRecords, noId, nil
+}
+
+// decodeRecordsFile decodes the records file at path, or stdin for "-",
+// along with the columns of each record, as the import does.
+func decodeRecordsFile(decoder RecordsHandler, collection *core.Collection, path string) ([]*fileRecord, error) {
+	reader := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	rowsDecoder, ok := decoder.(RowsHandler)
+	if !ok {
+		records, err := decoder.DecodeRecords(collection, reader)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]*fileRecord, len(records))
+		for i, record := range records {
+			result[i] = &fileRecord{record: record}
+		}
+		return result, nil
+	}
+
+	rows, err := rowsDecoder.DecodeRows(reader)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*fileRecord, len(rows))
+	for i, row := range rows {
+		record := core.NewRecord(collection)
+		if err := rowsDecoder.LoadRow(record, row); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		result[i] = &fileRecord{record: record, columns: slices.Collect(maps.Keys(row))}
+	}
+	return result, nil
+}
+
+// diffRecords compares the records of the files, keyed by id, and the
+// records without id with the records of the database, by the fields among
+// the columns of the files.
+func diffRecords(
+	collection *core.Collection,
+	fileRecords map[string]*fileRecord,
+	noId []*fileRecord,
+	dbRecords []*core.Record,
+) (*recordsDiff, error) {
+	diff := &recordsDiff{
+		Collection:     collection.Name,
+		OnlyInFiles:    []map[string]any{},
+		OnlyInDatabase: []map[string]any{},
+		Changed:        []*recordDiffChange{},
+	}
+
+	fields := []string{}
+	for _, field := range collection.Fields {
+		if diffRecordsIgnoredField(field) {
+			continue
+		}
+		fields = append(fields, field.GetName())
+	}
+
+	slices.SortFunc(dbRecords, func(a, b *core.Record) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	dbIds := map[string]bool{}
+	for _, dbRecord := range dbRecords {
+		dbIds[dbRecord.Id] = true
+		dbValues, err := recordDiffValues(dbRecord, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		f := fileRecords[dbRecord.Id]
+		if f == nil {
+			diff.OnlyInDatabase = append(diff.OnlyInDatabase, dbValues)
+			continue
+		}
+		compared := f.fields(fields)
+		fileValues, err := recordDiffValues(f.record, compared)
+		if err != nil {
+			return nil, err
+		}
+
+		change := &recordDiffChange{Id: dbRecord.Id, Fields: map[string]*recordDiffValue{}}
+		for _, name := range compared {
+			if !reflect.DeepEqual(dbValues[name], fileValues[name]) {
+				change.Fields[name] = &recordDiffValue{File: fileValues[name], Database: dbValues[name]}
+			}
+		}
+		if len(change.Fields) > 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(fileRecords)) {
+		if dbIds[id] {
+			continue
+		}
+		f := fileRecords[id]
+		values, err := recordDiffValues(f.record, f.fields(fields))
+		if err != nil {
+			return nil, err
+		}
+		diff.OnlyInFiles = append(diff.OnlyInFiles, values)
+	}
+	for _, f := range noId {
+		values, err := recordDiffValues(f.record, f.fields(fields))
+		if err != nil {
+			return nil, err
+		}
+		diff.OnlyInFiles = append(diff.OnlyInFiles, values)
+	}
+
+	return diff, nil
+}
+
+// diffRecordsIgnoredField reports whether the field is left out of records
+// diffs, since its values are secret or set on save.
+func diffRecordsIgnoredField(field core.Field) bool {
+	switch field.Type() {
+	case core.FieldTypePassword, core.FieldTypeAutodate:
+		return true
+	}
+	return field.GetName() == core.FieldNameTokenKey
+}
+
+// recordDiffValues returns the json representation of the field values of
+// the record, so that equal values compare equal regardless of their go
+// type, e.g. decoded and database datetimes.
+func recordDiffValues(record *core.Record, fields []string) (map[string]any, error) {
+	values := make(map[string]any, len(fields))
+	for _, name := range fields {
+		values[name] = record.Get(name)
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{}
+	return result, json.Unmarshal(raw, &result)
+}
+
+// writeText writes the diff in a git style text format, with "-" for the
+// records only in the files and "+" for the records only in the database.
+func (d *recordsDiff) writeText(w io.Writer, source string) {
+	fmt.Fprintf(w, "--- %s\n+++ database (%s)\n", source, d.Collection)
+	for _, values := range d.OnlyInFiles {
+		id, _ := values[core.FieldNameId].(string)
+		if id == "" {
+			id = "(no id)"
+		}
+		fmt.Fprintf(w, "- %s %s\n", id, diffJSON(values))
+	}
+	for _, values := range d.OnlyInDatabase {
+		fmt.Fprintf(w, "+ %s %s\n", values[core.FieldNameId], diffJSON(values))
+	}
+	for _, change := range d.Changed {
+		fmt.Fprintf(w, "~ %s\n", change.Id)
+		for _, name := range slices.Sorted(maps.Keys(change.Fields)) {
+			value := change.Fields[name]
+			fmt.Fprintf(w, "    %s: %s -> %s\n", name, diffJSON(value.File), diffJSON(value.Database))
+		}
+	}
+}
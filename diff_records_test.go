@@ -0,0 +1,161 @@
+package import_export
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	import_export_csv "github.com/pocketbuilds/import_export/handlers/csv"
+	import_export_json "github.com/pocketbuilds/import_export/handlers/json"
+	import_export_toml "github.com/pocketbuilds/import_export/handlers/toml"
+	import_export_yml "github.com/pocketbuilds/import_export/handlers/yml"
+)
+
+// testRecordsHandlers returns the built-in records handlers with their
+// defaults set.
+func testRecordsHandlers(t *testing.T, app core.App) []RowsHandler {
+	t.Helper()
+	result := []RowsHandler{}
+	for _, h := range []interface {
+		RowsHandler
+		PreValidate(app core.App) error
+	}{
+		&import_export_csv.Plugin{},
+		&import_export_json.Plugin{},
+		&import_export_toml.Plugin{},
+		&import_export_yml.Plugin{},
+	} {
+		if err := h.PreValidate(app); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, h)
+	}
+	return result
+}
+
+func TestDiffRecordsOfExport(t *testing.T) {
+	app := newTestApp(t)
+
+	posts := saveTestCollection(t, app, "posts",
+		&core.TextField{Name: "title"},
+		&core.TextField{Name: "secret", Hidden: true},
+	)
+	post := core.NewRecord(posts)
+	post.Set("title", "Hello")
+	post.Set("secret", "s")
+	if err := app.Save(post); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := core.NewRecord(users)
+	user.SetEmail("alice@example.com")
+	user.SetEmailVisibility(false)
+	user.SetPassword("1234567890")
+	user.Set("name", "Alice")
+	if err := app.Save(user); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, handler := range testRecordsHandlers(t, app) {
+		for _, collection := range []*core.Collection{posts, users} {
+			t.Run(handler.FileExtension()+" "+collection.Name, func(t *testing.T) {
+				records, err := app.FindAllRecords(collection)
+				if err != nil {
+					t.Fatal(err)
+				}
+				path := filepath.Join(t.TempDir(), collection.Name+"."+handler.FileExtension())
+				file, err := os.Create(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = handler.EncodeRecords(records, file)
+				file.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				fileRecords, noId, err := readRecordsFiles(handler, collection, []string{path})
+				if err != nil {
+					t.Fatal(err)
+				}
+				diff, err := diffRecords(collection, fileRecords, noId, records)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := len(diff.OnlyInFiles) + len(diff.OnlyInDatabase) + len(diff.Changed); n != 0 {
+					t.Errorf("Expected no differences, got %d: %+v", n, diff.Changed)
+				}
+			})
+		}
+	}
+}
+
+func TestDiffRecords(t *testing.T) {
+	app := newTestApp(t)
+	collection := saveTestCollection(t, app, "posts",
+		&core.TextField{Name: "title"},
+		&core.TextField{Name: "body"},
+	)
+	for _, id := range []string{"a00000000000001", "a00000000000002", "a00000000000003"} {
+		record := core.NewRecord(collection)
+		record.Id = id
+		record.Set("title", "old")
+		record.Set("body", "body")
+		if err := app.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbRecords, err := app.FindAllRecords(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	base := writeTestFile(t, dir, "posts.json", `[
+		{"id":"a00000000000001","title":"old","body":"body"},
+		{"id":"a00000000000002","title":"old","body":"body"},
+		{"id":"a00000000000004","title":"new"},
+		{"title":"no id"}
+	]`)
+	// the delta only changes the title, the missing body is not compared
+	delta := writeTestFile(t, dir, "posts.20260101T000000.delta.json", `[{"id":"a00000000000002","title":"changed"}]`)
+
+	fileRecords, noId, err := readRecordsFiles(handlers["json"].(RecordsHandler), collection, []string{base, delta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := diffRecords(collection, fileRecords, noId, dbRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onlyInFiles := []string{}
+	for _, values := range diff.OnlyInFiles {
+		id, _ := values["id"].(string)
+		onlyInFiles = append(onlyInFiles, id)
+		if _, ok := values["body"]; ok {
+			t.Errorf("Expected only the columns of the file for %q, got %v", id, values)
+		}
+	}
+	if expected := []string{"a00000000000004", ""}; !slices.Equal(onlyInFiles, expected) {
+		t.Errorf("Expected only in files %q, got %q", expected, onlyInFiles)
+	}
+
+	if len(diff.OnlyInDatabase) != 1 || diff.OnlyInDatabase[0]["id"] != "a00000000000003" {
+		t.Errorf("Expected only a00000000000003 in the database, got %v", diff.OnlyInDatabase)
+	}
+
+	if len(diff.Changed) != 1 || diff.Changed[0].Id != "a00000000000002" {
+		t.Fatalf("Expected only a00000000000002 to change, got %+v", diff.Changed)
+	}
+	title := diff.Changed[0].Fields["title"]
+	if len(diff.Changed[0].Fields) != 1 || title == nil || title.File != "changed" || title.Database != "old" {
+		t.Errorf("Expected the title to change from %q to %q, got %+v", "changed", "old", diff.Changed[0].Fields)
+	}
+}
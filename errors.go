@@ -5,4 +5,13 @@ import "errors"
 var (
 	ErrNoCollectionHandler = errors.New("no collection encoding handler was installed")
 	ErrNoRecordsHandler    = errors.New("no records encoding handler was installed")
+
+	// errDiffFound is returned by the diff commands if the files differ
+	// from the database.
+	errDiffFound = errors.New("differences found")
+	// errLintFailed is returned by the lint commands if there are errors, or
+	// warnings in strict mode.
+	errLintFailed = errors.New("lint failed")
+	// errDryRun rolls back the transaction of a dry run import.
+	errDryRun = errors.New("dry run")
 )
@@ -1,11 +1,16 @@
 package import_export
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/types"
 	"github.com/spf13/cobra"
@@ -27,6 +32,20 @@ func (p *Plugin) ExportCollectionsCommand(app core.App) *cobra.Command {
 	}
 	cmd.MarkFlagsMutuallyExclusive(p.CollectionsEncoding.Options()...)
 
+	collectionNames := []string{}
+	cmd.Flags().StringSliceVar(&collectionNames, "collection", collectionNames, "Collections to include in the export, otherwise exports all")
+
+	var force bool
+	cmd.Flags().BoolVar(&force, "force", force, "Export even if the collections directory contains files that were not created by an export, keeping them")
+
+	p.registerScopeFlags(cmd)
+
+	var portable bool
+	cmd.Flags().BoolVar(&portable, "portable", portable, "Refer to the related collections of relation fields by name instead of id")
+
+	var omitIds bool
+	cmd.Flags().BoolVar(&omitIds, "omit_ids", omitIds, "Leave out the collection and field ids of --portable exports")
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		// validate manually to catch changes to config due to cli flags
 		if err := p.Validate(); err != nil {
@@ -38,32 +57,89 @@ func (p *Plugin) ExportCollectionsCommand(app core.App) *cobra.Command {
 			return ErrNoCollectionHandler
 		}
 
-		fmt.Printf("Set to encoding: %s\n", p.CollectionsEncoding)
+		if omitIds && !portable {
+			return fmt.Errorf("--omit_ids requires --portable")
+		}
+
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.CollectionsEncoding)
 
 		msg := strings.Join([]string{
 			fmt.Sprintf(
 				"Do you really want to export all collections to %q?",
 				p.CollectionsDir,
 			),
-			"Warning: This will delete all the contents of the directory!",
+			"Warning: This will delete the files of previous exports in the directory!",
 		}, "\n")
 
+		if len(collectionNames) > 0 {
+			msg = strings.Join([]string{
+				fmt.Sprintf(
+					"Do you really want to export the listed collections to %q?",
+					p.CollectionsDir,
+				),
+				fmt.Sprintf(
+					"Collections: %s",
+					strings.Join(collectionNames, ", "),
+				),
+			}, "\n")
+		}
+
+		collections := []*core.Collection{}
+		collectionsQuery := app.CollectionQuery()
+		if len(collectionNames) > 0 {
+			collectionsQuery.AndWhere(dbx.In(
+				"name", sliceToAnySlice(collectionNames)...,
+			))
+		}
+		if err := collectionsQuery.All(&collections); err != nil {
+			return err
+		}
+
+		if len(collectionNames) > 0 && len(collections) != len(collectionNames) {
+			notExisting := []string{}
+			for _, name := range collectionNames {
+				if !slices.ContainsFunc(collections, func(c *core.Collection) bool {
+					return c.Name == name
+				}) {
+					notExisting = append(notExisting, name)
+				}
+			}
+			return fmt.Errorf("collection(s) do not exist: %s", strings.Join(notExisting, ", "))
+		}
+
 		if yes := confirm(msg, false); !yes {
-			fmt.Println("The command has been cancelled.")
+			fmt.Fprintln(os.Stderr, "The command has been cancelled.")
 			return nil
 		}
 
-		if err := os.RemoveAll(p.CollectionsDir); err != nil {
+		manifest, err := readOrNewManifest(p.CollectionsDir)
+		if err != nil {
 			return err
 		}
 
-		if err := os.MkdirAll(p.CollectionsDir, os.ModePerm); err != nil {
+		dir, err := openExportDir(p.CollectionsDir, force, nil, isCollectionsExportFile)
+		if err != nil {
 			return err
 		}
+		defer dir.close()
 
-		collections := []*core.Collection{}
-		if err := app.CollectionQuery().All(&collections); err != nil {
-			return err
+		names := map[string]string{}
+		if portable {
+			all, err := app.FindAllCollections()
+			if err != nil {
+				return err
+			}
+			for _, collection := range all {
+				names[collection.Id] = collection.Name
+			}
+		}
+
+		if len(collectionNames) == 0 {
+			// a full export supersedes all files of previous exports
+			for _, rel := range dir.existing {
+				dir.remove(rel)
+			}
+			manifest = newManifest()
 		}
 
 		for _, collection := range collections {
@@ -77,22 +153,61 @@ func (p *Plugin) ExportCollectionsCommand(app core.App) *cobra.Command {
 				collection.Updated = types.DateTime{} // set updated to zero value to reduce git diff
 			}
 
-			filepath := filepath.Join(p.CollectionsDir, fmt.Sprintf("%s.%s", collection.Name, encoder.FileExtension()))
-			file, err := os.Create(filepath)
+			fingerprint, err := collectionFingerprint(collection)
+			if err != nil {
+				return err
+			}
+
+			collectionId := collection.Id
+			if portable {
+				if err := makePortable(collection, names, omitIds); err != nil {
+					return err
+				}
+			}
+
+			filename := fmt.Sprintf("%s.%s", collection.Name, encoder.FileExtension())
+			path, err := dir.path(filename)
 			if err != nil {
 				return err
 			}
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			hash := sha256.New()
 			if err := func() (err error) {
 				defer func() {
 					err = file.Close()
 				}()
-				return encoder.EncodeCollection(collection, file)
+				return p.encodeCollection(encoder, collection, io.MultiWriter(file, hash))
 			}(); err != nil {
 				return err
 			}
+
+			manifest.set(&manifestFile{
+				Path:           filename,
+				CollectionId:   collectionId,
+				CollectionName: collection.Name,
+				SHA256:         hex.EncodeToString(hash.Sum(nil)),
+				Handler:        encoder.FileExtension(),
+				Fingerprint:    fingerprint,
+			})
+		}
+
+		if err := dir.commit(); err != nil {
+			return err
 		}
-		return nil
+
+		return manifest.write(p.CollectionsDir)
 	}
 
 	return cmd
 }
+
+// isCollectionsExportFile reports whether the path, relative to the
+// collections directory, is a collection file of any collection handler.
+func isCollectionsExportFile(rel string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(rel), ".")
+	_, ok := handlers[ext].(CollectionHandler)
+	return ok && !strings.ContainsRune(filepath.ToSlash(rel), '/')
+}
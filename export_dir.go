@@ -0,0 +1,199 @@
+package import_export
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Maximum number of unknown files listed when an export refuses to write
+// to a directory.
+const maxUnknownFiles = 5
+
+var errTooManyUnknownFiles = errors.New("too many unknown files")
+
+// exportDir stages the files of an export in a temporary directory next to
+// the export directory and moves them into place on commit, so a failed
+// export leaves the directory untouched. Only files of previous exports are
+// ever deleted from the export directory.
+type exportDir struct {
+	dir     string
+	staging string
+	// existing holds the paths of the files of previous exports, relative
+	// to dir.
+	existing []string
+	// removed holds the paths of the files of previous exports to delete
+	// on commit, relative to dir.
+	removed map[string]bool
+}
+
+// openExportDir creates the directory if needed, checks that it only
+// contains files of previous exports and creates the staging directory.
+// The files of previous exports are the files listed by the manifest of
+// the directory or, only if there is no manifest, the files reported by
+// isExportFile. Hidden files are never files of previous exports. Unknown
+// files result in an error unless force is set, in which case they are
+// kept. The keep subdirectories are skipped entirely, e.g. the profile
+// directories inside the records directory.
+func openExportDir(dir string, force bool, keep []string, isExportFile func(rel string) bool) (*exportDir, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, unknown := []string{}, []string{}
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if entry.IsDir() && slices.Contains(keep, filepath.Clean(path)) {
+			return filepath.SkipDir
+		}
+		switch {
+		case rel == manifestFilename || rel == exportStateFilename:
+			return nil // rewritten by the export
+		case entry.IsDir() && !isHidden(rel):
+			return nil
+		case entry.IsDir():
+			// don't look into hidden directories, e.g. .git
+			unknown = append(unknown, rel+string(filepath.Separator))
+			if len(unknown) > maxUnknownFiles && !force {
+				return errTooManyUnknownFiles
+			}
+			return filepath.SkipDir
+		case isHidden(rel):
+			// unknown
+		case m != nil && m.get(rel) != nil, m == nil && isExportFile(rel):
+			existing = append(existing, rel)
+			return nil
+		}
+		unknown = append(unknown, rel)
+		if len(unknown) > maxUnknownFiles && !force {
+			return errTooManyUnknownFiles
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errTooManyUnknownFiles) {
+		return nil, err
+	}
+
+	if len(unknown) > 0 && !force {
+		if len(unknown) > maxUnknownFiles {
+			unknown = append(unknown[:maxUnknownFiles], "...")
+		}
+		return nil, fmt.Errorf(
+			"%q contains files that were not created by an export, use --force to export anyway and keep them: %s",
+			dir,
+			strings.Join(unknown, ", "),
+		)
+	}
+
+	clean := filepath.Clean(dir)
+	staging, err := os.MkdirTemp(filepath.Dir(clean), "."+filepath.Base(clean)+".export-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &exportDir{
+		dir:      dir,
+		staging:  staging,
+		existing: existing,
+		removed:  map[string]bool{},
+	}, nil
+}
+
+// path returns the staging path of the file with the path relative to the
+// export directory, creating its parent directories.
+func (d *exportDir) path(rel string) (string, error) {
+	path := filepath.Join(d.staging, rel)
+	return path, os.MkdirAll(filepath.Dir(path), os.ModePerm)
+}
+
+// remove deletes the file of a previous export on commit, unless the export
+// writes a file with the same path. Paths of other files are ignored.
+func (d *exportDir) remove(rel string) {
+	rel = filepath.Clean(rel)
+	if slices.Contains(d.existing, rel) {
+		d.removed[rel] = true
+	}
+}
+
+// commit moves the staged files into the export directory and deletes the
+// removed files, along with their directories if they end up empty.
+func (d *exportDir) commit() error {
+	staged := []string{}
+	err := filepath.WalkDir(d.staging, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(d.staging, path)
+		if err != nil {
+			return err
+		}
+		staged = append(staged, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range staged {
+		target := filepath.Join(d.dir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(d.staging, rel), target); err != nil {
+			return err
+		}
+		delete(d.removed, rel)
+	}
+
+	for rel := range d.removed {
+		if err := os.Remove(filepath.Join(d.dir, rel)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if parent := filepath.Dir(rel); parent != "." {
+			os.Remove(filepath.Join(d.dir, parent)) // only succeeds if empty
+		}
+	}
+
+	return d.close()
+}
+
+// close deletes the staging directory.
+func (d *exportDir) close() error {
+	return os.RemoveAll(d.staging)
+}
+
+// isHidden reports whether any element of the relative path starts with a
+// dot.
+func isHidden(rel string) bool {
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(name, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFileAtomic writes the data to a temporary file next to path and
+// renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,132 @@
+package import_export
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpenExportDir(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		files           []string
+		manifest        string
+		keep            []string
+		force           bool
+		expectedError   bool
+		expectedDeleted []string
+	}{
+		{
+			name:            "only export files",
+			files:           []string{"posts.json", "users.json"},
+			expectedDeleted: []string{"posts.json", "users.json"},
+		},
+		{
+			name:          "unknown files",
+			files:         []string{"posts.json", "notes.txt"},
+			expectedError: true,
+		},
+		{
+			name:          "hidden files",
+			files:         []string{"posts.json", ".golangci.yml"},
+			expectedError: true,
+		},
+		{
+			name:            "hidden files with force",
+			files:           []string{"posts.json", ".golangci.yml", ".git/config"},
+			force:           true,
+			expectedDeleted: []string{"posts.json"},
+		},
+		{
+			name:          "files missing from the manifest",
+			files:         []string{"posts.json", "package.json"},
+			manifest:      `{"files":[{"path":"posts.json"}]}`,
+			expectedError: true,
+		},
+		{
+			name:            "files missing from the manifest with force",
+			files:           []string{"posts.json", "package.json", "docker-compose.yml"},
+			manifest:        `{"files":[{"path":"posts.json"}]}`,
+			force:           true,
+			expectedDeleted: []string{"posts.json"},
+		},
+		{
+			name:            "files without extension listed by the manifest",
+			files:           []string{"posts.json", "README"},
+			manifest:        `{"files":[{"path":"posts.json"},{"path":"README"}]}`,
+			expectedDeleted: []string{"README", "posts.json"},
+		},
+		{
+			name:            "kept subdirectories",
+			files:           []string{"posts.json", "demo/posts.json", "demo/notes.txt"},
+			keep:            []string{".", "demo"},
+			expectedDeleted: []string{"posts.json"},
+		},
+		{
+			name:            "kept paths of files",
+			files:           []string{"a.json", "b.json"},
+			keep:            []string{"a.json"},
+			expectedDeleted: []string{"a.json", "b.json"},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range s.files {
+				writeTestFile(t, dir, name, "{}")
+			}
+			if s.manifest != "" {
+				writeTestFile(t, dir, manifestFilename, s.manifest)
+			}
+
+			keep := []string{}
+			for _, rel := range s.keep {
+				keep = append(keep, filepath.Join(dir, rel))
+			}
+
+			d, err := openExportDir(dir, s.force, keep, isCollectionsExportFile)
+			if s.expectedError {
+				if err == nil {
+					d.close()
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a full export removes everything it can
+			for _, name := range s.files {
+				d.remove(name)
+			}
+			if err := d.commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			deleted := []string{}
+			for _, name := range s.files {
+				if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+					deleted = append(deleted, name)
+				}
+			}
+			slices.Sort(deleted)
+			if !slices.Equal(deleted, s.expectedDeleted) {
+				t.Errorf("Expected deleted files %v, got %v", s.expectedDeleted, deleted)
+			}
+			if s.manifest != "" {
+				if _, err := os.Stat(filepath.Join(dir, manifestFilename)); err != nil {
+					t.Errorf("Expected the manifest to be kept, got %v", err)
+				}
+			}
+
+			staging, _ := filepath.Glob(filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".export-*"))
+			if len(staging) > 0 {
+				t.Errorf("Expected the staging directory to be deleted, got %s", strings.Join(staging, ", "))
+			}
+		})
+	}
+}
@@ -1,14 +1,19 @@
 package import_export
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
 	"github.com/spf13/cobra"
 )
 
@@ -16,8 +21,9 @@ func (p *Plugin) ExportRecordsCommand(app core.App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "records",
 		Short:   "export records csv files to the records directory",
+		Long:    "export records csv files to the records directory, or to stdout with \"-\" for a single collection",
 		Aliases: []string{"record", "rec", "r"},
-		Args:    cobra.ExactArgs(0),
+		Args:    stdioArgs,
 	}
 
 	cmd.Flags().StringVar(&p.RecordsDir, "records_dir", p.RecordsDir, "Path to directory for records csv files")
@@ -30,6 +36,20 @@ func (p *Plugin) ExportRecordsCommand(app core.App) *cobra.Command {
 	collectionNames := []string{}
 	cmd.Flags().StringSliceVar(&collectionNames, "collection", collectionNames, "Collections to inlcude in the import, otherwise imports all")
 
+	var anonymize bool
+	cmd.Flags().BoolVar(&anonymize, "anonymize", anonymize, "Apply the configured anonymize rules to the exported records")
+
+	var since string
+	cmd.Flags().StringVar(&since, "since", since, "Only export records updated after the datetime, or \"last\" for the last export watermark")
+
+	var force bool
+	cmd.Flags().BoolVar(&force, "force", force, "Export even if the records directory contains files that were not created by an export, keeping them")
+
+	p.registerScopeFlags(cmd)
+
+	var chunkSize int
+	cmd.Flags().IntVar(&chunkSize, "chunk_size", chunkSize, "Maximum number of records per file, writing the files to a directory per collection (0 = no chunks)")
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		// validate manually to catch changes to config due to cli flags
 		if err := p.Validate(); err != nil {
@@ -41,7 +61,20 @@ func (p *Plugin) ExportRecordsCommand(app core.App) *cobra.Command {
 			return ErrNoRecordsHandler
 		}
 
-		fmt.Printf("Set to encoding: %s\n", p.RecordsEncoding)
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.RecordsEncoding)
+
+		if anonymize && len(p.Anonymize) == 0 {
+			return fmt.Errorf("no anonymize rules are configured")
+		}
+
+		var sinceDate types.DateTime
+		if since != "" && since != "last" {
+			var err error
+			if sinceDate, err = types.ParseDateTime(since); err != nil || sinceDate.IsZero() {
+				return fmt.Errorf("invalid --since value %q, must be a datetime or \"last\"", since)
+			}
+		}
+		deltaTime := time.Now().UTC().Format(deltaTimeFormat)
 
 		allCollections := []*core.Collection{}
 		collectionsQuery := app.CollectionQuery()
@@ -58,6 +91,9 @@ func (p *Plugin) ExportRecordsCommand(app core.App) *cobra.Command {
 		if err := collectionsQuery.All(&allCollections); err != nil {
 			return err
 		}
+		allCollections = slices.DeleteFunc(allCollections, func(c *core.Collection) bool {
+			return !p.includesRecordsOf(c)
+		})
 
 		if len(collectionNames) > 0 && len(allCollections) == 0 {
 			return fmt.Errorf("collection(s) do not exist: %s", strings.Join(collectionNames, ", "))
@@ -82,15 +118,51 @@ func (p *Plugin) ExportRecordsCommand(app core.App) *cobra.Command {
 			return fmt.Errorf("collection(s) do not exist: %s", strings.Join(notExisting, ", "))
 		}
 
+		if anonymize {
+			if err := p.checkAnonymizeRules(allCollections); err != nil {
+				return err
+			}
+		}
+
+		if len(args) == 1 {
+			if len(collectionNames) != 1 {
+				return fmt.Errorf("exactly one --collection is required to export to stdout")
+			}
+			if chunkSize > 0 || since == "last" {
+				return fmt.Errorf("--chunk_size and --since last cannot be used to export to stdout")
+			}
+			collection := allCollections[0]
+			exprs := []dbx.Expression{}
+			if !sinceDate.IsZero() {
+				exprs = append(exprs, updatedSinceExpr(sinceDate))
+			}
+			records, err := app.FindAllRecords(collection, exprs...)
+			if err != nil {
+				return err
+			}
+			if anonymize {
+				if err := p.anonymizeRecords(collection, records); err != nil {
+					return err
+				}
+			}
+			return encoder.EncodeRecords(records, os.Stdout)
+		}
+
 		msg := strings.Join([]string{
 			fmt.Sprintf(
 				"Do you really want to export records from all collections to %q?",
 				p.RecordsDir,
 			),
-			"Warning: This will delete all the contents of the directory!",
+			"Warning: This will delete the files of previous exports in the directory!",
 		}, "\n")
 
-		if len(collectionNames) > 0 {
+		if since != "" {
+			msg = fmt.Sprintf(
+				"Do you really want to export records changed since %s to %q?",
+				since,
+				p.RecordsDir,
+			)
+		} else if len(collectionNames) > 0 {
 			msg = strings.Join([]string{
 				fmt.Sprintf(
 					"Do you really want to export records from the listed collections to %q?",
@@ -104,49 +176,215 @@ func (p *Plugin) ExportRecordsCommand(app core.App) *cobra.Command {
 		}
 
 		if yes := confirm(msg, false); !yes {
-			fmt.Println("The command has been cancelled.")
+			fmt.Fprintln(os.Stderr, "The command has been cancelled.")
 			return nil
 		}
 
-		if len(collectionNames) == 0 {
-			if err := os.RemoveAll(p.RecordsDir); err != nil {
-				return err
-			}
+		existingManifest, err := readOrNewManifest(p.RecordsDir)
+		if err != nil {
+			return err
+		}
+
+		// profile directories inside the records directory are separate exports
+		keep, err := p.allProfileDirs()
+		if err != nil {
+			return err
+		}
+
+		dir, err := openExportDir(p.RecordsDir, force, keep, isRecordsExportFile)
+		if err != nil {
+			return err
 		}
+		defer dir.close()
 
-		if err := os.MkdirAll(p.RecordsDir, os.ModePerm); err != nil {
+		state, err := readExportState(p.RecordsDir)
+		if err != nil {
 			return err
 		}
 
+		existingFiles := exportedRecordsFiles(dir, encoder.FileExtension())
+
+		manifest := existingManifest
+		if len(collectionNames) == 0 && since == "" {
+			// a full export supersedes all files of previous exports
+			for _, rel := range dir.existing {
+				dir.remove(rel)
+			}
+			state = &exportState{Watermarks: map[string]types.DateTime{}}
+			manifest = newManifest()
+		}
+
 		for _, collection := range allCollections {
 
 			if collection.IsView() {
 				continue
 			}
 
-			records, err := app.FindAllRecords(collection)
+			fingerprint, err := collectionFingerprint(collection)
 			if err != nil {
 				return err
 			}
 
-			filename := fmt.Sprintf("%s.%s", collection.Name, p.RecordsEncoding)
+			updatedField, _ := collection.Fields.GetByName("updated").(*core.AutodateField)
+			if since != "" && updatedField == nil {
+				return fmt.Errorf("collection %s has no updated autodate field to export changes since", collection.Name)
+			}
+
+			watermark := sinceDate
+			if since == "last" {
+				watermark = state.Watermarks[collection.Name]
+			}
+
+			delta := ""
+			exprs := []dbx.Expression{}
+			if !watermark.IsZero() {
+				delta = deltaTime
+				exprs = append(exprs, updatedSinceExpr(watermark))
+			}
+
+			records, err := findRecordsChunk(app, collection, exprs, "", chunkSize)
+			if err != nil {
+				return err
+			}
 
-			if err := func() (err error) {
-				file, err := os.Create(filepath.Join(p.RecordsDir, filename))
+			if delta != "" && len(records) == 0 {
+				fmt.Fprintf(os.Stderr, "No changes to export for collection %s.\n", collection.Name)
+				continue
+			}
+
+			if delta == "" {
+				// a new base export supersedes all previous files of the collection
+				delete(state.Watermarks, collection.Name)
+				for _, f := range existingFiles {
+					if f.Collection == collection.Name {
+						rel, err := filepath.Rel(p.RecordsDir, f.Path)
+						if err != nil {
+							return err
+						}
+						dir.remove(rel)
+					}
+				}
+			}
+
+			chunk := 0
+			if chunkSize > 0 {
+				chunk = 1
+			}
+
+			for {
+				if anonymize {
+					if err := p.anonymizeRecords(collection, records); err != nil {
+						return err
+					}
+				}
+
+				filename := recordsFilename(collection.Name, delta, chunk, encoder.FileExtension())
+				path, err := dir.path(filename)
 				if err != nil {
 					return err
 				}
-				defer func() {
-					err = file.Close()
-				}()
-				return encoder.EncodeRecords(records, file)
-			}(); err != nil {
-				return err
+
+				checksum, err := writeRecordsFile(path, encoder, records)
+				if err != nil {
+					return err
+				}
+
+				manifest.set(&manifestFile{
+					Path:           filepath.ToSlash(filename),
+					CollectionId:   collection.Id,
+					CollectionName: collection.Name,
+					Rows:           len(records),
+					SHA256:         checksum,
+					Handler:        encoder.FileExtension(),
+					Fingerprint:    fingerprint,
+				})
+
+				if updatedField != nil {
+					for _, record := range records {
+						if updated := record.GetDateTime(updatedField.Name); updated.After(state.Watermarks[collection.Name]) {
+							state.Watermarks[collection.Name] = updated
+						}
+					}
+				}
+
+				if chunkSize <= 0 || len(records) < chunkSize {
+					break
+				}
+
+				records, err = findRecordsChunk(app, collection, exprs, records[len(records)-1].Id, chunkSize)
+				if err != nil {
+					return err
+				}
+				if len(records) == 0 {
+					break
+				}
+				chunk++
 			}
 		}
 
-		return nil
+		if err := dir.commit(); err != nil {
+			return err
+		}
+
+		if err := manifest.write(p.RecordsDir); err != nil {
+			return err
+		}
+
+		return state.write(p.RecordsDir)
 	}
 
 	return cmd
 }
+
+// writeRecordsFile encodes the records to the file at path and returns the
+// hex encoded sha256 checksum of the file.
+func writeRecordsFile(path string, encoder RecordsHandler, records []*core.Record) (checksum string, err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	hash := sha256.New()
+	if err := encoder.EncodeRecords(records, io.MultiWriter(file, hash)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// findRecordsChunk returns the next chunk of at most limit records ordered
+// by id, starting after the afterId. If limit is 0, all records are returned.
+func findRecordsChunk(
+	app core.App,
+	collection *core.Collection,
+	exprs []dbx.Expression,
+	afterId string,
+	limit int,
+) ([]*core.Record, error) {
+	if limit <= 0 {
+		return app.FindAllRecords(collection, exprs...)
+	}
+	query := app.RecordQuery(collection).
+		AndWhere(dbx.NewExp("[[id]] > {:afterId}", dbx.Params{"afterId": afterId})).
+		OrderBy("id ASC").
+		Limit(int64(limit))
+	for _, expr := range exprs {
+		query.AndWhere(expr)
+	}
+	records := []*core.Record{}
+	if err := query.All(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// updatedSinceExpr returns an expression matching the records updated
+// after the datetime.
+func updatedSinceExpr(since types.DateTime) dbx.Expression {
+	return dbx.NewExp("[[updated]] > {:since}", dbx.Params{
+		"since": since.String(),
+	})
+}
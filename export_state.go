@@ -0,0 +1,50 @@
+package import_export
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Name of the file in the records directory that keeps track of the
+// incremental export watermarks.
+const exportStateFilename = ".export_state.json"
+
+type exportState struct {
+	// Watermarks holds the latest exported updated datetime, keyed by
+	// collection name.
+	Watermarks map[string]types.DateTime `json:"watermarks"`
+}
+
+// readExportState reads the export state from dir. A missing state file
+// results in an empty state.
+func readExportState(dir string) (*exportState, error) {
+	state := &exportState{
+		Watermarks: map[string]types.DateTime{},
+	}
+	data, err := os.ReadFile(filepath.Join(dir, exportStateFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Watermarks == nil {
+		state.Watermarks = map[string]types.DateTime{}
+	}
+	return state, nil
+}
+
+func (s *exportState) write(dir string) error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, exportStateFilename), data)
+}
@@ -0,0 +1,545 @@
+package import_export
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/spf13/cobra"
+)
+
+var fakeWords = strings.Fields(`lorem ipsum dolor sit amet consectetur
+	adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna
+	aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris nisi
+	aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate
+	velit esse cillum fugiat nulla pariatur excepteur sint occaecat cupidatat`)
+
+// Datetime the generated dates are relative to, so seeded runs generate the
+// same records regardless of when they run.
+var fakeBaseDate = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Maximum number of repetitions of the unbounded pattern repeat operators.
+const fakeMaxRepeat = 6
+
+// Maximum number of attempts to generate a new value of a unique field.
+const fakeMaxUniqueAttempts = 100
+
+func (p *Plugin) ExportFakeCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fake",
+		Short: "export generated fake records to the records directory",
+		Long:  "export generated fake records to the records directory, to stdout with \"-\" for a single collection, or insert them into the database",
+		Args:  stdioArgs,
+	}
+
+	cmd.Flags().StringVar(&p.RecordsDir, "records_dir", p.RecordsDir, "Path to directory for records csv files")
+
+	for _, opt := range p.RecordsEncoding.Options() {
+		cmd.Flags().VarPF(p.RecordsEncoding, opt, "", fmt.Sprintf("%s encoding", opt)).NoOptDefVal = opt
+	}
+	cmd.MarkFlagsMutuallyExclusive(p.RecordsEncoding.Options()...)
+
+	counts := map[string]int{}
+	cmd.Flags().StringToIntVar(&counts, "count", counts, "Number of records to generate per collection, e.g. posts=10000,users=500")
+	cmd.MarkFlagRequired("count")
+
+	var seed int64
+	cmd.Flags().Int64Var(&seed, "seed", seed, "Seed of the generator to generate the same records again (default random)")
+
+	var insert bool
+	cmd.Flags().BoolVar(&insert, "insert", insert, "Insert the records into the database instead of writing them to files")
+
+	var force bool
+	cmd.Flags().BoolVar(&force, "force", force, "Export even if the records directory contains files that were not created by an export, keeping them")
+
+	batchSize := 500
+	cmd.Flags().IntVar(&batchSize, "batch_size", batchSize, "Number of records per insert statement of --insert")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// validate manually to catch changes to config due to cli flags
+		if err := p.Validate(); err != nil {
+			return err
+		}
+
+		encoder, ok := handlers[p.RecordsEncoding.String()].(RecordsHandler)
+		if !ok {
+			return ErrNoRecordsHandler
+		}
+
+		useStdout := len(args) == 1
+		if useStdout && (insert || len(counts) != 1) {
+			return fmt.Errorf("exactly one --count collection and no --insert are required to export to stdout")
+		}
+
+		names := []string{}
+		for name := range counts {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		collections := []*core.Collection{}
+		for _, name := range names {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return fmt.Errorf("collection %s: %w", name, err)
+			}
+			if collection.IsView() {
+				return fmt.Errorf("cannot generate records for view collection %s", name)
+			}
+			if counts[name] <= 0 {
+				return fmt.Errorf("count of collection %s must be positive", name)
+			}
+			collections = append(collections, collection)
+		}
+		// generate the related collections first to relate to their records
+		collections = sortCollectionsByRelations(collections)
+
+		if !cmd.Flags().Changed("seed") {
+			seed = time.Now().UnixNano()
+		}
+		fmt.Fprintf(os.Stderr, "Using seed %d\n", seed)
+
+		f := newFaker(app, seed, collections)
+
+		if useStdout {
+			records, err := f.records(collections[0], counts[names[0]])
+			if err != nil {
+				return err
+			}
+			return encoder.EncodeRecords(records, os.Stdout)
+		}
+
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.RecordsEncoding)
+
+		msg := fmt.Sprintf(
+			"Do you really want to export fake records of the listed collections to %q?",
+			p.RecordsDir,
+		)
+		if insert {
+			msg = "Do you really want to insert fake records into the listed collections?"
+		}
+		msg += fmt.Sprintf("\nCollections: %s", strings.Join(names, ", "))
+
+		if yes := confirm(msg, false); !yes {
+			fmt.Fprintln(os.Stderr, "The command has been cancelled.")
+			return nil
+		}
+
+		importer := &recordsImporter{
+			p:         p,
+			app:       app,
+			ctx:       withImport(cmd.Context()),
+			hooks:     hooksModelOnly,
+			fast:      true,
+			batchSize: batchSize,
+		}
+
+		var m *manifest
+		var dir *exportDir
+		var existingFiles []*recordsFile
+		if !insert {
+			var err error
+			if m, err = readOrNewManifest(p.RecordsDir); err != nil {
+				return err
+			}
+			keep, err := p.allProfileDirs()
+			if err != nil {
+				return err
+			}
+			dir, err = openExportDir(p.RecordsDir, force, keep, isRecordsExportFile)
+			if err != nil {
+				return err
+			}
+			defer dir.close()
+			existingFiles = exportedRecordsFiles(dir, encoder.FileExtension())
+		}
+
+		for _, collection := range collections {
+			records, err := f.records(collection, counts[collection.Name])
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Generated %d records for collection %s.\n", len(records), collection.Name)
+
+			if insert {
+				if err := importer.saveRecords(collection, records, "fake", false); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// the generated records supersede all previous files of the collection
+			for _, existing := range existingFiles {
+				if existing.Collection == collection.Name {
+					rel, err := filepath.Rel(p.RecordsDir, existing.Path)
+					if err != nil {
+						return err
+					}
+					dir.remove(rel)
+				}
+			}
+
+			filename := recordsFilename(collection.Name, "", 0, encoder.FileExtension())
+			path, err := dir.path(filename)
+			if err != nil {
+				return err
+			}
+			checksum, err := writeRecordsFile(path, encoder, records)
+			if err != nil {
+				return err
+			}
+
+			fingerprint, err := collectionFingerprint(collection)
+			if err != nil {
+				return err
+			}
+			m.set(&manifestFile{
+				Path:           filepath.ToSlash(filename),
+				CollectionId:   collection.Id,
+				CollectionName: collection.Name,
+				Rows:           len(records),
+				SHA256:         checksum,
+				Handler:        encoder.FileExtension(),
+				Fingerprint:    fingerprint,
+			})
+		}
+
+		if insert {
+			return nil
+		}
+		if err := dir.commit(); err != nil {
+			return err
+		}
+		return m.write(p.RecordsDir)
+	}
+
+	return cmd
+}
+
+// faker generates records with values that satisfy the field options of
+// their collections.
+type faker struct {
+	app  core.App
+	rand *rand.Rand
+	// planned holds the ids of the collections to generate records for.
+	planned map[string]bool
+	// ids holds the ids of the generated records, keyed by collection id.
+	ids map[string][]string
+	// existingIds caches the ids of existing records of the related
+	// collections that are not generated, keyed by collection id.
+	existingIds map[string][]string
+	// used holds the generated values of the unique fields, lowercased and
+	// keyed by collection id and field name.
+	used map[string]map[string]bool
+}
+
+func newFaker(app core.App, seed int64, collections []*core.Collection) *faker {
+	f := &faker{
+		app:         app,
+		rand:        rand.New(rand.NewPCG(uint64(seed), uint64(seed))),
+		planned:     map[string]bool{},
+		ids:         map[string][]string{},
+		existingIds: map[string][]string{},
+		used:        map[string]map[string]bool{},
+	}
+	for _, collection := range collections {
+		f.planned[collection.Id] = true
+	}
+	return f
+}
+
+// records generates count records of the collection.
+func (f *faker) records(collection *core.Collection, count int) ([]*core.Record, error) {
+	records := make([]*core.Record, 0, count)
+	for n := range count {
+		record := core.NewRecord(collection)
+		for _, field := range collection.Fields {
+			value, err := f.value(collection, field, record, n)
+			if err != nil {
+				return nil, fmt.Errorf("collection %s field %s: %w", collection.Name, field.GetName(), err)
+			}
+			if value != nil {
+				record.Set(field.GetName(), value)
+			}
+		}
+		f.ids[collection.Id] = append(f.ids[collection.Id], record.Id)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// value generates the value of the field for the n-th record, or nil if the
+// field is left to its default, e.g. autodate and file fields. The fields
+// before the field, e.g. the id, are already set on the record. The values
+// of unique fields are regenerated until they differ from all values
+// generated for the field before.
+func (f *faker) value(collection *core.Collection, field core.Field, record *core.Record, n int) (any, error) {
+	if !isUniqueField(collection, field) {
+		return f.generate(collection, field, record, n, 0)
+	}
+
+	key := collection.Id + "." + field.GetName()
+	if f.used[key] == nil {
+		f.used[key] = map[string]bool{}
+	}
+	for attempt := range fakeMaxUniqueAttempts {
+		value, err := f.generate(collection, field, record, n, attempt)
+		if err != nil || value == nil {
+			return value, err
+		}
+		normalized := strings.ToLower(fmt.Sprint(value))
+		if !f.used[key][normalized] {
+			f.used[key][normalized] = true
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("no new unique value after %d attempts", fakeMaxUniqueAttempts)
+}
+
+// generate generates a value of the field for the n-th record, see value.
+// attempt counts the values of a unique field generated for the record so
+// far.
+func (f *faker) generate(collection *core.Collection, field core.Field, record *core.Record, n int, attempt int) (any, error) {
+	switch field := field.(type) {
+	case *core.TextField:
+		if pattern := cmp.Or(field.AutogeneratePattern, field.Pattern); pattern != "" {
+			return f.regex(pattern)
+		}
+		text := f.words(1 + f.rand.IntN(6))
+		if isUniqueField(collection, field) {
+			// the number keeps the values apart, a random one once the
+			// value with the number of the record is taken, e.g. because
+			// of Max
+			number := n + 1
+			if attempt > 0 {
+				number = 1 + f.rand.IntN(1_000_000)
+			}
+			text = fmt.Sprintf("%d %s", number, text)
+		}
+		for len(text) < field.Min {
+			text += " " + f.word()
+		}
+		if field.Max > 0 && len(text) > field.Max {
+			text = strings.TrimSpace(text[:field.Max])
+		}
+		return text, nil
+	case *core.NumberField:
+		lo, hi := 0.0, 1000.0
+		if field.Min != nil {
+			lo = *field.Min
+			hi = max(hi, lo+1000)
+		}
+		if field.Max != nil {
+			hi = *field.Max
+			if field.Min == nil {
+				lo = min(lo, hi-1000)
+			}
+		}
+		if field.OnlyInt {
+			lo, hi = math.Ceil(lo), math.Floor(hi)
+			if lo > hi {
+				return nil, fmt.Errorf("no integer between min %v and max %v", *field.Min, *field.Max)
+			}
+			return lo + float64(f.rand.Int64N(int64(hi-lo)+1)), nil
+		}
+		return math.Round((lo+f.rand.Float64()*(hi-lo))*100) / 100, nil
+	case *core.BoolField:
+		return f.rand.IntN(2) == 1, nil
+	case *core.EmailField:
+		return fmt.Sprintf("%s%d@%s", f.word(), n+1, f.domain(field.OnlyDomains)), nil
+	case *core.URLField:
+		return fmt.Sprintf("https://%s/%s/%d", f.domain(field.OnlyDomains), f.word(), n+1), nil
+	case *core.EditorField:
+		return "<p>" + f.words(8+f.rand.IntN(24)) + "</p>", nil
+	case *core.DateField:
+		lo, hi := fakeBaseDate, fakeBaseDate.AddDate(5, 0, 0)
+		if !field.Min.IsZero() {
+			lo = field.Min.Time()
+			hi = lo.AddDate(5, 0, 0)
+		}
+		if !field.Max.IsZero() {
+			hi = field.Max.Time()
+			if field.Min.IsZero() {
+				lo = hi.AddDate(-5, 0, 0)
+			}
+		}
+		seconds := int64(hi.Sub(lo).Seconds())
+		return types.ParseDateTime(lo.Add(time.Duration(f.rand.Int64N(max(seconds, 1))) * time.Second))
+	case *core.SelectField:
+		if len(field.Values) == 0 {
+			return nil, nil
+		}
+		if !field.IsMultiple() {
+			return field.Values[f.rand.IntN(len(field.Values))], nil
+		}
+		count := 1 + f.rand.IntN(min(field.MaxSelect, len(field.Values)))
+		values := []string{}
+		for _, i := range f.rand.Perm(len(field.Values))[:count] {
+			values = append(values, field.Values[i])
+		}
+		return values, nil
+	case *core.JSONField:
+		return map[string]any{
+			"title": f.words(3),
+			"count": f.rand.IntN(1000),
+		}, nil
+	case *core.RelationField:
+		ids, err := f.relationIds(field.CollectionId)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 && field.Required && field.CollectionId == collection.Id {
+			// the first record of a required relation to its own collection
+			// has no other record to relate to
+			ids = []string{record.Id}
+		}
+		if len(ids) == 0 {
+			if field.Required {
+				return nil, fmt.Errorf("no records of the related collection to relate to")
+			}
+			return nil, nil
+		}
+		if !field.IsMultiple() {
+			return ids[f.rand.IntN(len(ids))], nil
+		}
+		lo := max(field.MinSelect, 1)
+		hi := max(min(field.MaxSelect, len(ids)), lo)
+		count := min(lo+f.rand.IntN(hi-lo+1), len(ids))
+		picked := map[string]bool{}
+		values := []string{}
+		for len(values) < count {
+			id := ids[f.rand.IntN(len(ids))]
+			if !picked[id] {
+				picked[id] = true
+				values = append(values, id)
+			}
+		}
+		return values, nil
+	}
+
+	// autodate, file, password and unknown fields
+	return nil, nil
+}
+
+// isUniqueField reports whether the field has a unique index or is the
+// primary key.
+func isUniqueField(collection *core.Collection, field core.Field) bool {
+	if text, ok := field.(*core.TextField); ok && text.PrimaryKey {
+		return true
+	}
+	return dbutils.HasSingleColumnUniqueIndex(field.GetName(), collection.Indexes)
+}
+
+// relationIds returns the ids of the records to relate to in the
+// collection: the records generated so far, if the collection is
+// generated, otherwise some of the existing records.
+func (f *faker) relationIds(collectionId string) ([]string, error) {
+	if f.planned[collectionId] {
+		return f.ids[collectionId], nil
+	}
+	if ids, ok := f.existingIds[collectionId]; ok {
+		return ids, nil
+	}
+	collection, err := f.app.FindCachedCollectionByNameOrId(collectionId)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	err = f.app.DB().Select("id").From(collection.Name).OrderBy("id ASC").Limit(1000).Column(&ids)
+	if err != nil {
+		return nil, err
+	}
+	f.existingIds[collectionId] = ids
+	return ids, nil
+}
+
+func (f *faker) word() string {
+	return fakeWords[f.rand.IntN(len(fakeWords))]
+}
+
+func (f *faker) words(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = f.word()
+	}
+	return strings.Join(words, " ")
+}
+
+func (f *faker) domain(domains []string) string {
+	if len(domains) > 0 {
+		return domains[f.rand.IntN(len(domains))]
+	}
+	return "example.com"
+}
+
+// regex generates a random string matching the pattern, like
+// security.RandomStringByRegex but with the seeded source.
+func (f *faker) regex(pattern string) (string, error) {
+	r, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	sb := &strings.Builder{}
+	if err := f.writeRegex(r, sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (f *faker) writeRegex(r *syntax.Regexp, sb *strings.Builder) error {
+	switch r.Op {
+	case syntax.OpLiteral:
+		sb.WriteString(string(r.Rune))
+	case syntax.OpCharClass:
+		i := f.rand.IntN(len(r.Rune) / 2)
+		lo, hi := r.Rune[i*2], r.Rune[i*2+1]
+		sb.WriteRune(lo + rune(f.rand.IntN(int(hi-lo)+1)))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteByte("abcdefghijklmnopqrstuvwxyz0123456789"[f.rand.IntN(36)])
+	case syntax.OpCapture:
+		return f.writeRegex(r.Sub[0], sb)
+	case syntax.OpConcat:
+		for _, sub := range r.Sub {
+			if err := f.writeRegex(sub, sb); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		return f.writeRegex(r.Sub[f.rand.IntN(len(r.Sub))], sb)
+	case syntax.OpQuest, syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+		lo, hi := r.Min, r.Max
+		switch r.Op {
+		case syntax.OpQuest:
+			lo, hi = 0, 1
+		case syntax.OpStar:
+			lo, hi = 0, fakeMaxRepeat
+		case syntax.OpPlus:
+			lo, hi = 1, fakeMaxRepeat
+		}
+		if hi < 0 {
+			hi = lo + fakeMaxRepeat
+		}
+		for range lo + f.rand.IntN(hi-lo+1) {
+			if err := f.writeRegex(r.Sub[0], sb); err != nil {
+				return err
+			}
+		}
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		// zero width
+	default:
+		return fmt.Errorf("unsupported pattern operator %s", r.Op)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package import_export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestFakerNumberRange(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		field         *core.NumberField
+		expectedError bool
+	}{
+		{"default range", &core.NumberField{Name: "n", OnlyInt: true}, false},
+		{"integer range", &core.NumberField{Name: "n", OnlyInt: true, Min: types.Pointer(2.0), Max: types.Pointer(2.0)}, false},
+		{"fraction range", &core.NumberField{Name: "n", Min: types.Pointer(0.2), Max: types.Pointer(0.8)}, false},
+		{"empty integer range", &core.NumberField{Name: "n", OnlyInt: true, Min: types.Pointer(0.2), Max: types.Pointer(0.8)}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			// not saved, as the app rejects decimal limits of integer fields,
+			// unlike imports without validation
+			collection := core.NewBaseCollection("numbers")
+			collection.Fields.Add(s.field)
+
+			f := newFaker(nil, 1, []*core.Collection{collection})
+			records, err := f.records(collection, 20)
+			if s.expectedError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, record := range records {
+				value := record.GetFloat("n")
+				if s.field.Min != nil && value < *s.field.Min || s.field.Max != nil && value > *s.field.Max {
+					t.Errorf("Expected a value within the range, got %v", value)
+				}
+			}
+		})
+	}
+}
+
+func TestFakerRequiredSelfRelation(t *testing.T) {
+	app := newTestApp(t)
+	collection := saveTestCollection(t, app, "categories", &core.TextField{Name: "name"})
+	collection.Fields.Add(&core.RelationField{Name: "parent", CollectionId: collection.Id, Required: true, MaxSelect: 1})
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFaker(app, 1, []*core.Collection{collection})
+	records, err := f.records(collection, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent := records[0].GetString("parent"); parent != records[0].Id {
+		t.Errorf("Expected the first record to relate to itself, got %q", parent)
+	}
+
+	im := newTestImporter(t, app)
+	im.hooks, im.fast = hooksModelOnly, true
+	if err := im.saveRecords(collection, records, "fake", false); err != nil {
+		t.Fatal(err)
+	}
+	total, err := app.CountRecords(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 10 {
+		t.Errorf("Expected 10 inserted records, got %d", total)
+	}
+}
+
+func TestFakerUniquePattern(t *testing.T) {
+	app := newTestApp(t)
+	collection := saveTestCollection(t, app, "codes", &core.TextField{Name: "code", Pattern: `^[ab][ab]$`})
+	collection.AddIndex("idx_codes_code", true, "code", "")
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFaker(app, 1, []*core.Collection{collection})
+	records, err := f.records(collection, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, record := range records {
+		code := record.GetString("code")
+		if seen[code] {
+			t.Errorf("Expected unique codes, got %q twice", code)
+		}
+		seen[code] = true
+	}
+
+	// only 4 values match the pattern
+	_, err = f.records(collection, 1)
+	if err == nil || !strings.Contains(err.Error(), "unique") {
+		t.Errorf("Expected a unique value error, got %v", err)
+	}
+}
+
+func TestFakerUniqueValues(t *testing.T) {
+	scenarios := []struct {
+		name  string
+		field core.Field
+		// count is the number of unique values of the field
+		count int
+	}{
+		{"integers", &core.NumberField{Name: "v", OnlyInt: true, Min: types.Pointer(1.0), Max: types.Pointer(3.0)}, 3},
+		{"truncated text", &core.TextField{Name: "v", Max: 1}, 9},
+		{"bools", &core.BoolField{Name: "v"}, 2},
+		{"select", &core.SelectField{Name: "v", MaxSelect: 1, Values: []string{"a", "b", "c"}}, 3},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			collection := core.NewBaseCollection("values")
+			collection.Fields.Add(s.field)
+			collection.AddIndex("idx_values_v", true, "v", "")
+
+			f := newFaker(nil, 1, []*core.Collection{collection})
+			records, err := f.records(collection, s.count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen := map[string]bool{}
+			for _, record := range records {
+				value := record.GetString("v")
+				if seen[value] {
+					t.Errorf("Expected unique values, got %q twice", value)
+				}
+				seen[value] = true
+			}
+
+			// all values are taken
+			_, err = f.records(collection, 1)
+			if err == nil || !strings.Contains(err.Error(), "unique") {
+				t.Errorf("Expected a unique value error, got %v", err)
+			}
+		})
+	}
+}
@@ -16,12 +16,33 @@ type RecordsHandler interface {
 	DecodeRecords(collection *core.Collection, reader io.Reader) ([]*core.Record, error)
 }
 
+// RowsHandler is an optional extension of RecordsHandler for handlers that
+// can decode the records data into raw rows keyed by column name, allowing
+// the rows to be inspected and remapped before they are loaded into records.
+// EncodeRows encodes decoded rows as they are, e.g. to write the rejects of
+// an import in the format of its source.
+type RowsHandler interface {
+	RecordsHandler
+	DecodeRows(reader io.Reader) ([]map[string]any, error)
+	LoadRow(record *core.Record, row map[string]any) error
+	EncodeRows(rows []map[string]any, writer io.Writer) error
+}
+
 type CollectionHandler interface {
 	Handler
 	EncodeCollection(collection *core.Collection, writer io.Writer) error
 	DecodeCollection(reader io.Reader) (map[string]any, error)
 }
 
+// CollectionDataHandler is an optional extension of CollectionHandler for
+// handlers that can encode generic collection data, allowing values that
+// the collection model does not serialize, such as secret placeholders, to
+// be exported.
+type CollectionDataHandler interface {
+	CollectionHandler
+	EncodeCollectionData(data map[string]any, writer io.Writer) error
+}
+
 var handlers = map[string]Handler{}
 
 func RegisterHandler(h Handler) {
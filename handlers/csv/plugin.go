@@ -3,19 +3,37 @@ package import_export_csv
 import (
 	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
+	"slices"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/spf13/cast"
 )
 
 type Plugin struct {
 	// Delimiter character to use for the csv.
 	//   - default: ","
 	Delimiter string `json:"delimiter"`
+	// Layouts to parse date and autodate values with, in the go time format,
+	// after the RFC 3339 and PocketBase formats.
+	//   - default: ["2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"]
+	DateFormats []string `json:"date_formats"`
+	// Timezone of the date and autodate values without an offset, which are
+	// converted to UTC.
+	//   - default: "UTC"
+	Timezone string `json:"timezone"`
+
+	location *time.Location
 }
 
+// Layouts of date values with an explicit offset (including the PocketBase
+// format), which are always tried first.
+var offsetDateFormats = []string{time.RFC3339, "2006-01-02 15:04:05Z07:00"}
+
 // Name implements xpb.Plugin.
 func (p *Plugin) Name() string {
 	return "import_export_csv"
@@ -37,6 +55,8 @@ func (p *Plugin) Description() string {
 // PreValidate implements xpb.PreValidator.
 func (p *Plugin) PreValidate(app core.App) error {
 	p.Delimiter = ","
+	p.DateFormats = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+	p.Timezone = "UTC"
 	return nil
 }
 
@@ -47,12 +67,21 @@ func (p *Plugin) Validate() error {
 			validation.Required,
 			validation.Length(1, 1),
 		),
+		validation.Field(&p.Timezone,
+			validation.Required,
+			validation.By(func(value any) error {
+				_, err := time.LoadLocation(value.(string))
+				return err
+			}),
+		),
 	)
 }
 
 // Init implements xpb.Plugin.
 func (p *Plugin) Init(app core.App) error {
-	return nil
+	var err error
+	p.location, err = time.LoadLocation(p.Timezone)
+	return err
 }
 
 // FileExtension implements import_export.Handler.
@@ -62,43 +91,154 @@ func (p *Plugin) FileExtension() string {
 
 // DecodeRecords implements import_export.RecordsHandler.
 func (p *Plugin) DecodeRecords(collection *core.Collection, reader io.Reader) ([]*core.Record, error) {
+	rows, err := p.DecodeRows(reader)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*core.Record, 0, len(rows))
+	for i, row := range rows {
+		record := core.NewRecord(collection)
+		if err := p.LoadRow(record, row); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// DecodeRows implements import_export.RowsHandler.
+func (p *Plugin) DecodeRows(reader io.Reader) ([]map[string]any, error) {
 	csvReader := csv.NewReader(reader)
 	csvReader.Comma = rune(p.Delimiter[0])
-	fields, err := csvReader.Read()
+	columns, err := csvReader.Read()
 	if err != nil {
 		return nil, err
 	}
-	rows, err := csvReader.ReadAll()
+	csvRows, err := csvReader.ReadAll()
 	if err != nil {
 		return nil, err
 	}
-	records := make([]*core.Record, 0, len(rows))
-	for _, row := range rows {
-		record := core.NewRecord(collection)
-		for i, fieldName := range fields {
-			field := collection.Fields.GetByName(fieldName)
-			if field == nil {
-				continue
-			}
+	rows := make([]map[string]any, 0, len(csvRows))
+	for _, csvRow := range csvRows {
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
 			var value any
-			value = row[i]
+			value = csvRow[i]
 			if value == "\"\"" {
 				value = nil
 			}
-			switch field.Type() {
-			case core.FieldTypeAutodate:
-				date, err := types.ParseDateTime(value)
-				if err != nil {
-					break
+			row[column] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// LoadRow implements import_export.RowsHandler.
+func (p *Plugin) LoadRow(record *core.Record, row map[string]any) error {
+	errs := validation.Errors{}
+	for fieldName, value := range row {
+		field := record.Collection().Fields.GetByName(fieldName)
+		if field == nil {
+			continue
+		}
+		switch field.Type() {
+		case core.FieldTypeAutodate, core.FieldTypeDate:
+			date, err := p.parseDate(cast.ToString(value))
+			if err != nil {
+				errs[fieldName] = err
+				break
+			}
+			record.SetRaw(fieldName, date)
+		default:
+			record.Set(fieldName, value)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// parseDate parses the date value with the offset formats, or with the
+// configured formats in the configured timezone, and converts it to UTC.
+// An empty value results in the zero datetime.
+func (p *Plugin) parseDate(value string) (types.DateTime, error) {
+	if value == "" {
+		return types.DateTime{}, nil
+	}
+	for _, layout := range offsetDateFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return types.ParseDateTime(t.UTC())
+		}
+	}
+	location := p.location
+	if location == nil {
+		location = time.UTC
+	}
+	for _, layout := range p.DateFormats {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return types.ParseDateTime(t.UTC())
+		}
+	}
+	return types.DateTime{}, validation.NewError(
+		"validation_invalid_date",
+		fmt.Sprintf("Cannot parse %q as a date", value),
+	)
+}
+
+// EncodeRows implements import_export.RowsHandler. The columns are sorted by
+// name, with empty values for the columns missing from a row and the
+// decoded null value for nil.
+func (p *Plugin) EncodeRows(rows []map[string]any, writer io.Writer) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = rune(p.Delimiter[0])
+	defer csvWriter.Flush()
+
+	columns := []string{}
+	for _, row := range rows {
+		for column := range row {
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+	}
+	slices.Sort(columns)
+
+	if err := csvWriter.Write(columns); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		csvRow := make([]string, 0, len(columns))
+		for _, column := range columns {
+			value, ok := row[column]
+			switch v := value.(type) {
+			case string:
+				csvRow = append(csvRow, v)
+			case nil:
+				if ok {
+					csvRow = append(csvRow, "\"\"")
+				} else {
+					csvRow = append(csvRow, "")
 				}
-				record.SetRaw(fieldName, date)
 			default:
-				record.Set(fieldName, value)
+				valueBytes, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				csvRow = append(csvRow, string(valueBytes))
 			}
 		}
-		records = append(records, record)
+		if err := csvWriter.Write(csvRow); err != nil {
+			return err
+		}
 	}
-	return records, nil
+	return nil
 }
 
 // EncodeRecords implements import_export.RecordsHandler.
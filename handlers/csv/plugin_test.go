@@ -0,0 +1,103 @@
+package import_export_csv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	_ "time/tzdata"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func newTestPlugin(t *testing.T, timezone string, formats ...string) *Plugin {
+	t.Helper()
+	p := &Plugin{}
+	if err := p.PreValidate(nil); err != nil {
+		t.Fatal(err)
+	}
+	p.Timezone = timezone
+	if len(formats) > 0 {
+		p.DateFormats = formats
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(nil); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestParseDate(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		timezone      string
+		formats       []string
+		value         string
+		expected      string
+		expectedError bool
+	}{
+		{"empty", "UTC", nil, "", "", false},
+		{"rfc 3339", "UTC", nil, "2024-03-01T12:00:00+02:00", "2024-03-01 10:00:00.000Z", false},
+		{"pocketbase format", "UTC", nil, "2024-03-01 12:00:00.123Z", "2024-03-01 12:00:00.123Z", false},
+		{"space with offset", "UTC", nil, "2024-03-01 12:00:00-05:00", "2024-03-01 17:00:00.000Z", false},
+		{"offset ignores the timezone", "Europe/Berlin", nil, "2024-03-01T12:00:00Z", "2024-03-01 12:00:00.000Z", false},
+		{"default format in utc", "UTC", nil, "2024-03-01 12:00:00", "2024-03-01 12:00:00.000Z", false},
+		{"default format in timezone", "Europe/Berlin", nil, "2024-03-01T12:00:00", "2024-03-01 11:00:00.000Z", false},
+		{"date only in timezone", "Europe/Berlin", nil, "2024-03-01", "2024-02-29 23:00:00.000Z", false},
+		{"custom format in winter", "Europe/Berlin", []string{"02/01/2006 15:04"}, "01/03/2024 12:00", "2024-03-01 11:00:00.000Z", false},
+		{"custom format in summer", "Europe/Berlin", []string{"02/01/2006 15:04"}, "01/07/2024 12:00", "2024-07-01 10:00:00.000Z", false},
+		{"custom format replaces the defaults", "UTC", []string{"02/01/2006"}, "2024-03-01 12:00:00", "", true},
+		{"invalid", "UTC", nil, "yesterday", "", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			p := newTestPlugin(t, s.timezone, s.formats...)
+			date, err := p.parseDate(s.value)
+			if s.expectedError {
+				if err == nil {
+					t.Fatalf("Expected error, got %v", date)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if date.String() != s.expected {
+				t.Errorf("Expected %q, got %q", s.expected, date.String())
+			}
+		})
+	}
+}
+
+func TestLoadRowDates(t *testing.T) {
+	collection := core.NewBaseCollection("posts")
+	collection.Fields.Add(
+		&core.TextField{Name: "title"},
+		&core.DateField{Name: "published"},
+	)
+	p := newTestPlugin(t, "Europe/Berlin")
+
+	record := core.NewRecord(collection)
+	if err := p.LoadRow(record, map[string]any{"title": "Hello", "published": "2024-03-01 12:00:00"}); err != nil {
+		t.Fatal(err)
+	}
+	if published := record.GetDateTime("published").String(); published != "2024-03-01 11:00:00.000Z" {
+		t.Errorf("Expected the date in UTC, got %q", published)
+	}
+
+	record = core.NewRecord(collection)
+	err := p.LoadRow(record, map[string]any{"title": "Hello", "published": "yesterday"})
+	var errs validation.Errors
+	if !errors.As(err, &errs) || errs["published"] == nil {
+		t.Fatalf("Expected a published error, got %v", err)
+	}
+	if !strings.Contains(errs["published"].Error(), `Cannot parse "yesterday" as a date`) {
+		t.Errorf("Expected an invalid date error, got %q", errs["published"])
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected only the published error, got %v", errs)
+	}
+}
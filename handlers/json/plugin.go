@@ -61,14 +61,14 @@ func (p *Plugin) FileExtension() string {
 
 // DecodeRecords implements import_export.RecordsHandler.
 func (p *Plugin) DecodeRecords(collection *core.Collection, reader io.Reader) ([]*core.Record, error) {
-	var rawJsonRecords []json.RawMessage
-	if err := json.NewDecoder(reader).Decode(&rawJsonRecords); err != nil {
+	rows, err := p.DecodeRows(reader)
+	if err != nil {
 		return nil, err
 	}
-	records := make([]*core.Record, 0, len(rawJsonRecords))
-	for _, raw := range rawJsonRecords {
+	records := make([]*core.Record, 0, len(rows))
+	for _, row := range rows {
 		record := core.NewRecord(collection)
-		if err := json.Unmarshal(raw, &record); err != nil {
+		if err := p.LoadRow(record, row); err != nil {
 			return nil, err
 		}
 		records = append(records, record)
@@ -76,6 +76,30 @@ func (p *Plugin) DecodeRecords(collection *core.Collection, reader io.Reader) ([
 	return records, nil
 }
 
+// DecodeRows implements import_export.RowsHandler.
+func (p *Plugin) DecodeRows(reader io.Reader) ([]map[string]any, error) {
+	var rows []map[string]any
+	if err := json.NewDecoder(reader).Decode(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// LoadRow implements import_export.RowsHandler.
+func (p *Plugin) LoadRow(record *core.Record, row map[string]any) error {
+	record.Load(row)
+	return nil
+}
+
+// EncodeRows implements import_export.RowsHandler.
+func (p *Plugin) EncodeRows(rows []map[string]any, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	if p.RecordsPrefix != "" || p.RecordsIndent != "" {
+		encoder.SetIndent(p.RecordsPrefix, p.RecordsIndent)
+	}
+	return encoder.Encode(rows)
+}
+
 // EncodeRecords implements import_export.RecordsHandler.
 func (p *Plugin) EncodeRecords(records []*core.Record, writer io.Writer) error {
 	encoder := json.NewEncoder(writer)
@@ -102,3 +126,12 @@ func (p *Plugin) EncodeCollection(collection *core.Collection, writer io.Writer)
 	}
 	return encoder.Encode(collection)
 }
+
+// EncodeCollectionData implements import_export.CollectionDataHandler.
+func (p *Plugin) EncodeCollectionData(data map[string]any, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	if p.CollectionPrefix != "" || p.CollectionIndent != "" {
+		encoder.SetIndent(p.CollectionPrefix, p.CollectionIndent)
+	}
+	return encoder.Encode(data)
+}
@@ -59,21 +59,47 @@ func (p *Plugin) FileExtension() string {
 
 // DecodeRecords implements import_export.RecordsHandler.
 func (p *Plugin) DecodeRecords(collection *core.Collection, reader io.Reader) ([]*core.Record, error) {
+	rows, err := p.DecodeRows(reader)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*core.Record, 0, len(rows))
+	for _, row := range rows {
+		record := core.NewRecord(collection)
+		if err := p.LoadRow(record, row); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// DecodeRows implements import_export.RowsHandler.
+func (p *Plugin) DecodeRows(reader io.Reader) ([]map[string]any, error) {
 	var tomlData map[string][]map[string]any
 	if _, err := toml.NewDecoder(reader).Decode(&tomlData); err != nil {
 		return nil, err
 	}
-	recordsData, ok := tomlData[p.RecordsArrayKey]
+	rows, ok := tomlData[p.RecordsArrayKey]
 	if !ok {
 		return nil, fmt.Errorf("toml records array key \"%s\" does not exist", p.RecordsArrayKey)
 	}
-	records := make([]*core.Record, 0, len(recordsData))
-	for _, data := range recordsData {
-		record := core.NewRecord(collection)
-		record.Load(data)
-		records = append(records, record)
-	}
-	return records, nil
+	return rows, nil
+}
+
+// LoadRow implements import_export.RowsHandler.
+func (p *Plugin) LoadRow(record *core.Record, row map[string]any) error {
+	record.Load(row)
+	return nil
+}
+
+// EncodeRows implements import_export.RowsHandler.
+func (p *Plugin) EncodeRows(rows []map[string]any, writer io.Writer) error {
+	encoder := toml.NewEncoder(writer)
+	encoder.Indent = p.RecordsIndent
+	return encoder.Encode(map[string]any{
+		p.RecordsArrayKey: rows,
+	})
 }
 
 // EncodeRecords implements import_export.RecordsHandler.
@@ -112,6 +138,11 @@ func (p *Plugin) EncodeCollection(collection *core.Collection, writer io.Writer)
 	if err := json.Unmarshal(jsonBytes, &collectionData); err != nil {
 		return err
 	}
+	return p.EncodeCollectionData(collectionData, writer)
+}
+
+// EncodeCollectionData implements import_export.CollectionDataHandler.
+func (p *Plugin) EncodeCollectionData(collectionData map[string]any, writer io.Writer) error {
 	encoder := toml.NewEncoder(writer)
 	encoder.Indent = p.CollectionIndent
 	return encoder.Encode(collectionData)
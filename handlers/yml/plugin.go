@@ -54,25 +54,58 @@ func (p *Plugin) FileExtension() string {
 
 // DecodeRecords implements import_export.RecordsHandler.
 func (p *Plugin) DecodeRecords(collection *core.Collection, reader io.Reader) ([]*core.Record, error) {
-	var recordsData []map[string]any
-	if err := yaml.NewDecoder(reader).Decode(&recordsData); err != nil {
+	rows, err := p.DecodeRows(reader)
+	if err != nil {
 		return nil, err
 	}
-	records := make([]*core.Record, 0, len(recordsData))
-	for _, data := range recordsData {
+	records := make([]*core.Record, 0, len(rows))
+	for _, row := range rows {
 		record := core.NewRecord(collection)
-		record.Load(data)
+		if err := p.LoadRow(record, row); err != nil {
+			return nil, err
+		}
 		records = append(records, record)
 	}
 	return records, nil
 }
 
+// DecodeRows implements import_export.RowsHandler.
+func (p *Plugin) DecodeRows(reader io.Reader) ([]map[string]any, error) {
+	var rows []map[string]any
+	if err := yaml.NewDecoder(reader).Decode(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// LoadRow implements import_export.RowsHandler.
+func (p *Plugin) LoadRow(record *core.Record, row map[string]any) error {
+	record.Load(row)
+	return nil
+}
+
+// EncodeRows implements import_export.RowsHandler.
+func (p *Plugin) EncodeRows(rows []map[string]any, writer io.Writer) error {
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(p.RecordsIndent)
+	return encoder.Encode(rows)
+}
+
 // EncodeRecords implements import_export.RecordsHandler.
 func (p *Plugin) EncodeRecords(records []*core.Record, writer io.Writer) error {
 	var recordsData []map[string]any
 	for _, record := range records {
 		recordsData = append(recordsData, record.PublicExport())
 	}
+	// round trip through json to encode types like types.DateTime as strings
+	jsonBytes, err := json.Marshal(recordsData)
+	if err != nil {
+		return err
+	}
+	recordsData = nil
+	if err := json.Unmarshal(jsonBytes, &recordsData); err != nil {
+		return err
+	}
 	encoder := yaml.NewEncoder(writer)
 	encoder.SetIndent(p.RecordsIndent)
 	return encoder.Encode(recordsData)
@@ -97,6 +130,11 @@ func (p *Plugin) EncodeCollection(collection *core.Collection, writer io.Writer)
 	if err := json.Unmarshal(jsonBytes, &collectionData); err != nil {
 		return err
 	}
+	return p.EncodeCollectionData(collectionData, writer)
+}
+
+// EncodeCollectionData implements import_export.CollectionDataHandler.
+func (p *Plugin) EncodeCollectionData(collectionData map[string]any, writer io.Writer) error {
 	encoder := yaml.NewEncoder(writer)
 	encoder.SetIndent(p.CollectionIndent)
 	return encoder.Encode(collectionData)
@@ -0,0 +1,78 @@
+package import_export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// newTestApp returns an empty app with all migrations applied.
+func newTestApp(t *testing.T) *tests.TestApp {
+	t.Helper()
+	app, err := tests.NewTestApp(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(app.Cleanup)
+	return app
+}
+
+// newTestPlugin returns the plugin with the defaults set for the app.
+func newTestPlugin(t *testing.T, app core.App) *Plugin {
+	t.Helper()
+	p := &Plugin{}
+	if err := p.PreValidate(app); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// newTestImporter returns a records importer for json files.
+func newTestImporter(t *testing.T, app core.App) *recordsImporter {
+	t.Helper()
+	return &recordsImporter{
+		p:             newTestPlugin(t, app),
+		app:           app,
+		ctx:           withImport(context.Background()),
+		decoder:       handlers["json"].(RecordsHandler),
+		hooks:         hooksAll,
+		matchKeyCache: map[string]map[string]string{},
+		idMap:         map[string]map[string]string{},
+		refs:          map[string]map[string]*recordRef{},
+		batchSize:     500,
+	}
+}
+
+// saveTestCollection saves a base collection with the fields and the
+// created and updated autodate fields.
+func saveTestCollection(t *testing.T, app core.App, name string, fields ...core.Field) *core.Collection {
+	t.Helper()
+	collection := core.NewBaseCollection(name)
+	collection.Fields.Add(fields...)
+	collection.Fields.Add(
+		&core.AutodateField{Name: "created", OnCreate: true},
+		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+	)
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+	return collection
+}
+
+// writeTestFile writes the file to the directory, creating its parent
+// directories, and returns its path.
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
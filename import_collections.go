@@ -5,8 +5,12 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbuilds/import_export/flags"
+	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 )
 
@@ -26,65 +30,125 @@ func (p *Plugin) ImportCollectionsCommand(app core.App) *cobra.Command {
 	}
 	cmd.MarkFlagsMutuallyExclusive(p.CollectionsEncoding.Options()...)
 
+	collectionNames := []string{}
+	cmd.Flags().StringSliceVar(&collectionNames, "collection", collectionNames, "Collections to include in the import, otherwise imports all")
+
+	var deleteMissing bool
+	cmd.Flags().BoolVar(&deleteMissing, "delete_missing", deleteMissing, "Delete the existing collections and their records missing from the collections directory")
+	cmd.MarkFlagsMutuallyExclusive("collection", "delete_missing")
+
+	p.registerScopeFlags(cmd)
+
+	oauth2 := flags.NewRadioValue(oauth2Skip, oauth2Merge, oauth2Replace)
+	cmd.Flags().Var(oauth2, "oauth2", "Import of the oauth2 config: skip, merge (keeps the existing client secrets) or replace (default skip, or replace with --include_oauth2)")
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 		// validate manually to catch changes to config due to cli flags
 		if err := p.Validate(); err != nil {
 			return err
 		}
 
+		if err := oauth2.Validate(); err != nil {
+			return fmt.Errorf("oauth2: %w", err)
+		}
+		if !cmd.Flags().Changed("oauth2") && p.IncludeOauth2 {
+			if err := oauth2.Set(oauth2Replace); err != nil {
+				return fmt.Errorf("oauth2: %w", err)
+			}
+		}
+
 		decoder, ok := handlers[p.CollectionsEncoding.String()].(CollectionHandler)
 		if !ok {
 			return ErrNoCollectionHandler
 		}
 
-		fmt.Printf("Set to encoding: %s\n", p.CollectionsEncoding)
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.CollectionsEncoding)
+
+		msg := fmt.Sprintf("Do you really want to import collections from %q?", p.CollectionsDir)
+		if len(collectionNames) > 0 {
+			msg = strings.Join([]string{
+				fmt.Sprintf(
+					"Do you really want to import the listed collections from %q?",
+					p.CollectionsDir,
+				),
+				fmt.Sprintf(
+					"Collections: %s",
+					strings.Join(collectionNames, ", "),
+				),
+			}, "\n")
+		}
+		if deleteMissing {
+			msg += "\nWarning this will delete all current collections missing from the directory and their records!"
+		}
 
-		if yes := confirm(
-			fmt.Sprintf("Do you really want to import collections from %q", p.CollectionsDir),
-			false,
-		); !yes {
-			fmt.Println("The command has been cancelled.")
+		if yes := confirm(msg, false); !yes {
+			fmt.Fprintln(os.Stderr, "The command has been cancelled.")
 			return nil
 		}
 
 		if p.AutoBackup {
 			name := backupName("import_collections")
-			fmt.Printf("Making backup %s\n", name)
+			fmt.Fprintf(os.Stderr, "Making backup %s\n", name)
 			err := app.CreateBackup(cmd.Context(), name)
 			if err != nil {
 				return err
 			}
 		}
 
-		collections := []map[string]any{}
+		collections, err := readCollectionFiles(p.CollectionsDir, decoder)
+		if err != nil {
+			return err
+		}
 
-		err = filepath.Walk(p.CollectionsDir, func(path string, info fs.FileInfo, err error) error {
-			if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
-				return err
-			}
+		if len(collectionNames) > 0 {
+			collections = slices.DeleteFunc(collections, func(c map[string]any) bool {
+				return !slices.Contains(collectionNames, cast.ToString(c["name"]))
+			})
+		}
 
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
+		if !p.System {
+			collections = slices.DeleteFunc(collections, func(c map[string]any) bool {
+				if !cast.ToBool(c["system"]) {
+					return false
+				}
+				fmt.Fprintf(os.Stderr, "Skipping system collection %s, use --system to import it.\n", c["name"])
+				return true
+			})
+		}
 
-			collection, err := decoder.DecodeCollection(file)
-			if err != nil {
-				return err
-			}
+		// resolve relation targets and ids omitted by portable exports
+		if err := resolvePortable(app, collections); err != nil {
+			return err
+		}
 
-			if !p.IncludeOauth2 {
+		switch oauth2.String() {
+		case oauth2Skip:
+			for _, collection := range collections {
 				delete(collection, "oauth2") // don't write over oauth2 settings
 			}
+		case oauth2Merge:
+			if err := mergeOAuth2(app, collections); err != nil {
+				return err
+			}
+		}
 
-			collections = append(collections, collection)
-			return nil
-		})
-		if err != nil {
+		if err := expandEnv(collections); err != nil {
 			return err
 		}
 
-		if err := app.ImportCollections(collections, true); err != nil {
+		if len(collectionNames) > 0 && len(collections) != len(collectionNames) {
+			notFound := []string{}
+			for _, name := range collectionNames {
+				if !slices.ContainsFunc(collections, func(c map[string]any) bool {
+					return c["name"] == name
+				}) {
+					notFound = append(notFound, name)
+				}
+			}
+			return fmt.Errorf("collection(s) not found in %q: %s", p.CollectionsDir, strings.Join(notFound, ", "))
+		}
+
+		if err := app.ImportCollections(collections, deleteMissing); err != nil {
 			return err
 		}
 
@@ -93,3 +157,39 @@ func (p *Plugin) ImportCollectionsCommand(app core.App) *cobra.Command {
 
 	return cmd
 }
+
+// readCollectionFiles decodes the collection files of the directory.
+func readCollectionFiles(dir string, decoder CollectionHandler) ([]map[string]any, error) {
+	collections := []map[string]any{}
+	err := walkCollectionFiles(dir, decoder, func(path string, collection map[string]any, err error) error {
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		collections = append(collections, collection)
+		return nil
+	})
+	return collections, err
+}
+
+// walkCollectionFiles calls fn with the path and the decoded collection, or
+// the decoding error, of each collection file of the directory.
+func walkCollectionFiles(
+	dir string,
+	decoder CollectionHandler,
+	fn func(path string, collection map[string]any, err error) error,
+) error {
+	return filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != "."+decoder.FileExtension() || info.Name() == manifestFilename {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		collection, err := decoder.DecodeCollection(file)
+		return fn(path, collection, err)
+	})
+}
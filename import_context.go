@@ -0,0 +1,24 @@
+package import_export
+
+import "context"
+
+type importContextKey struct{}
+
+// IsImport reports whether the context belongs to a records import, so app
+// hooks can tell imported records apart from regular traffic, e.g.:
+//
+//	app.OnRecordCreate("users").BindFunc(func(e *core.RecordEvent) error {
+//		if import_export.IsImport(e.Context) {
+//			return e.Next() // skip the welcome email
+//		}
+//		...
+//	})
+func IsImport(ctx context.Context) bool {
+	is, _ := ctx.Value(importContextKey{}).(bool)
+	return is
+}
+
+// withImport returns a copy of the context flagged as a records import.
+func withImport(ctx context.Context) context.Context {
+	return context.WithValue(ctx, importContextKey{}, true)
+}
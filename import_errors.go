@@ -0,0 +1,148 @@
+package import_export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Name of the report file listing the records that failed to import.
+const importErrorsFilename = "import_errors.jsonl"
+
+type importError struct {
+	Collection string `json:"collection"`
+	Source     string `json:"source"`
+	// Row is the 1-based index of the record in the source, or 0 if the
+	// whole source failed to import.
+	Row      int    `json:"row"`
+	RecordId string `json:"record_id,omitempty"`
+	Error    string `json:"error"`
+	// Fields holds the validation errors per field, if any.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// importReport collects the errors of an import that continues on error,
+// along with the rejected records so they can be fixed and re-imported.
+type importReport struct {
+	errors  []*importError
+	rejects map[string][]*core.Record
+	// rejectRows holds the source rows of the rejected records, keyed by
+	// collection name, for the records decoded from rows.
+	rejectRows map[string][]map[string]any
+}
+
+func newImportReport() *importReport {
+	return &importReport{
+		rejects:    map[string][]*core.Record{},
+		rejectRows: map[string][]map[string]any{},
+	}
+}
+
+// add records the error of the record at the row of the source. The record
+// may be nil if the whole source failed to import. data is the source row
+// of the record, if it was decoded from one, which is written to the
+// rejects instead of the record so that the rejects can be re-imported with
+// the same mapping.
+func (r *importReport) add(
+	collection *core.Collection,
+	source string,
+	row int,
+	record *core.Record,
+	data map[string]any,
+	err error,
+) {
+	e := &importError{
+		Collection: collection.Name,
+		Source:     source,
+		Row:        row,
+		Error:      err.Error(),
+	}
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		e.Fields = make(map[string]string, len(validationErrs))
+		for name, fieldErr := range validationErrs {
+			e.Fields[name] = fieldErr.Error()
+		}
+	}
+	if record != nil {
+		e.RecordId = record.Id
+		r.rejects[collection.Name] = append(r.rejects[collection.Name], record)
+		if data != nil {
+			r.rejectRows[collection.Name] = append(r.rejectRows[collection.Name], data)
+		}
+	}
+	r.errors = append(r.errors, e)
+}
+
+// has reports whether there are errors for the collection.
+func (r *importReport) has(collection *core.Collection) bool {
+	for _, e := range r.errors {
+		if e.Collection == collection.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// write writes the error report and a reject file per collection, encoded
+// with the encoder, to dir. The rejects are encoded from their source rows
+// if the encoder is a RowsHandler and all of them were decoded from rows.
+func (r *importReport) write(dir string, encoder RecordsHandler) error {
+	if err := os.MkdirAll(filepath.Join(dir, "rejects"), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := func() (err error) {
+		file, err := os.Create(filepath.Join(dir, importErrorsFilename))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = file.Close()
+		}()
+		jsonEncoder := json.NewEncoder(file)
+		for _, e := range r.errors {
+			if err := jsonEncoder.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	for collection, records := range r.rejects {
+		path := filepath.Join(dir, "rejects", recordsFilename(collection, "", 0, encoder.FileExtension()))
+		if err := func() (err error) {
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = file.Close()
+			}()
+			rows := r.rejectRows[collection]
+			if rowsEncoder, ok := encoder.(RowsHandler); ok && len(rows) == len(records) {
+				return rowsEncoder.EncodeRows(rows, file)
+			}
+			return encoder.EncodeRecords(records, file)
+		}(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importRecordError wraps the error of a record import with its location.
+func importRecordError(source string, row int, record *core.Record, err error) error {
+	if record != nil && record.Id != "" {
+		return fmt.Errorf("%s row %d (id %s): %w", source, row, record.Id, err)
+	}
+	return fmt.Errorf("%s row %d: %w", source, row, err)
+}
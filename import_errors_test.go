@@ -0,0 +1,72 @@
+package import_export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestImportReportRejectsSourceRows(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		mapping  recordsMapping
+		file     string
+		expected []map[string]any
+	}{
+		{
+			"without mapping",
+			nil,
+			`[{"id":"a00000000000001","title":"ok"},{"id":"a00000000000002","title":""}]`,
+			[]map[string]any{{"id": "a00000000000002", "title": ""}},
+		},
+		{
+			"with mapping",
+			recordsMapping{"posts": {Columns: map[string]string{"Headline": "title", "Legacy": ""}}},
+			`[{"id":"a00000000000001","Headline":"ok"},{"id":"a00000000000002","Headline":"","Legacy":1}]`,
+			[]map[string]any{{"id": "a00000000000002", "Headline": "", "Legacy": float64(1)}},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			collection := saveTestCollection(t, app, "posts", &core.TextField{Name: "title", Required: true})
+			path := writeTestFile(t, t.TempDir(), "posts.json", s.file)
+
+			im := newTestImporter(t, app)
+			im.mapping = s.mapping
+			im.report = newImportReport()
+
+			records, err := im.decodeFile(collection, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := im.saveRecords(collection, records, path, false); err != nil {
+				t.Fatal(err)
+			}
+			if len(im.report.errors) != 1 {
+				t.Fatalf("Expected 1 error, got %d", len(im.report.errors))
+			}
+
+			dir := t.TempDir()
+			if err := im.report.write(dir, im.decoder); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(filepath.Join(dir, "rejects", "posts.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rejects := []map[string]any{}
+			if err := json.Unmarshal(raw, &rejects); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rejects, s.expected) {
+				t.Errorf("Expected rejects %v, got %v", s.expected, rejects)
+			}
+		})
+	}
+}
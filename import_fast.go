@@ -0,0 +1,278 @@
+package import_export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Maximum number of bound parameters of a single sqlite statement.
+const maxQueryParams = 32766
+
+// fastRow is a prepared record waiting to be inserted.
+type fastRow struct {
+	row    int
+	record *core.Record
+	data   map[string]any
+	// columns holds the columns to update if the record already exists.
+	columns []string
+	// touched holds the autodate columns to set to the current time if the
+	// record already exists.
+	touched []string
+}
+
+// insertRecords inserts the records of a file with batched multi-row
+// inserts inside a transaction. The app hooks are skipped, but the field
+// interceptors, depending on the hooks option, and, unless disabled, the
+// field validations still run.
+func (im *recordsImporter) insertRecords(
+	collection *core.Collection,
+	records []*core.Record,
+	source string,
+	upsert bool,
+) error {
+	start := time.Now()
+	inserted := 0
+
+	err := im.app.RunInTransaction(func(txApp core.App) error {
+		batch := []*fastRow{}
+		// ids of the batch, to flush it before inserting a record relating
+		// to one of them, since relations are validated against the db
+		batchIds := map[string]bool{}
+		relations := selfRelations(collection)
+
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			defer func() {
+				batch = batch[:0]
+				clear(batchIds)
+			}()
+			if err := insertBatch(txApp, collection, batch, upsert); err == nil {
+				inserted += len(batch)
+				return nil
+			}
+			// retry row by row to find the failing record(s)
+			for _, r := range batch {
+				if err := insertBatch(txApp, collection, []*fastRow{r}, upsert); err != nil {
+					if im.report == nil {
+						return importRecordError(source, r.row, r.record, err)
+					}
+					im.report.add(collection, source, r.row, r.record, im.sourceRows[r.record], err)
+					continue
+				}
+				inserted++
+			}
+			return nil
+		}
+
+		for _, i := range saveOrder(collection, records) {
+			record := records[i]
+			if relatesTo(record, relations, batchIds) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			data, err := im.prepareInsert(txApp, record)
+			if err != nil {
+				if im.report == nil {
+					return importRecordError(source, i+1, record, err)
+				}
+				im.report.add(collection, source, i+1, record, im.sourceRows[record], err)
+				continue
+			}
+			columns, touched := im.updateColumns(collection, record)
+			// a batch updates the same columns of all its existing records
+			if upsert && len(batch) > 0 && (!slices.Equal(batch[0].columns, columns) || !slices.Equal(batch[0].touched, touched)) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			batch = append(batch, &fastRow{row: i + 1, record: record, data: data, columns: columns, touched: touched})
+			batchIds[record.Id] = true
+			if len(batch) >= max(1, min(im.batchSize, maxQueryParams/len(data))) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	fmt.Fprintf(
+		os.Stderr,
+		"Inserted %d records to collection %s in %s (%.0f rows/sec).\n",
+		inserted,
+		collection.Name,
+		elapsed.Round(time.Millisecond),
+		float64(inserted)/elapsed.Seconds(),
+	)
+
+	return nil
+}
+
+// relatesTo reports whether the record relates to any of the ids through
+// the relation fields.
+func relatesTo(record *core.Record, relations []*core.RelationField, ids map[string]bool) bool {
+	for _, relation := range relations {
+		for _, id := range record.GetStringSlice(relation.Name) {
+			if ids[id] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prepareInsert runs the field interceptors of a record create, unless the
+// hooks option is none, and the field validations, without triggering any
+// app hooks, and returns the record data to insert.
+func (im *recordsImporter) prepareInsert(app core.App, record *core.Record) (map[string]any, error) {
+	if err := im.prepareRecord(record); err != nil {
+		return nil, err
+	}
+
+	if im.hooks != hooksNone {
+		for _, field := range record.Collection().Fields {
+			interceptor, ok := field.(core.RecordInterceptor)
+			if !ok {
+				continue
+			}
+			err := interceptor.Intercept(im.ctx, app, record, core.InterceptorActionCreate, func() error {
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !im.p.NoValidate {
+		errs := validation.Errors{}
+		for _, field := range record.Collection().Fields {
+			if err := validateFastValue(im.ctx, app, field, record); err != nil {
+				errs[field.GetName()] = err
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	}
+
+	return record.DBExport(app)
+}
+
+// validateFastValue validates the record value of the field. The primary
+// key is validated without the case-insensitive existence check, which
+// can't use the table index and would scan the table for every record;
+// duplicates are rejected by the primary key constraint on insert instead.
+// Relations of a record to itself are accepted.
+func validateFastValue(ctx context.Context, app core.App, field core.Field, record *core.Record) error {
+	if relation, ok := field.(*core.RelationField); ok && relation.CollectionId == record.Collection().Id {
+		ids := record.GetStringSlice(relation.Name)
+		if slices.Contains(ids, record.Id) {
+			// the record relates to itself, which only exists once inserted
+			others := slices.DeleteFunc(ids, func(id string) bool { return id == record.Id })
+			if len(others) == 0 {
+				return nil
+			}
+			clone := record.Clone()
+			clone.Set(relation.Name, others)
+			return field.ValidateValue(ctx, app, clone)
+		}
+	}
+
+	text, ok := field.(*core.TextField)
+	if !ok || !text.PrimaryKey {
+		return field.ValidateValue(ctx, app, record)
+	}
+	if strings.ContainsAny(record.Id, "/\\") {
+		return validation.NewError("validation_pk_forbidden", "The record primary key contains forbidden characters.")
+	}
+	return text.ValidatePlainValue(record.Id)
+}
+
+// insertBatch inserts the rows with a single multi-row insert statement.
+// If upsert is true, the update columns of the first row are updated for
+// the existing rows instead, and its touched columns set to the current
+// time.
+func insertBatch(app core.App, collection *core.Collection, rows []*fastRow, upsert bool) error {
+	columns := make([]string, 0, len(rows[0].data))
+	for column := range rows[0].data {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	params := dbx.Params{}
+	values := make([]string, len(rows))
+	for i, r := range rows {
+		placeholders := make([]string, len(columns))
+		for j, column := range columns {
+			name := fmt.Sprintf("p%d_%d", i, j)
+			params[name] = r.data[column]
+			placeholders[j] = "{:" + name + "}"
+		}
+		values[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "[[" + column + "]]"
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO {{%s}} (%s) VALUES %s",
+		collection.Name,
+		strings.Join(quoted, ", "),
+		strings.Join(values, ", "),
+	)
+
+	if upsert {
+		sets := make([]string, 0, len(rows[0].columns)+len(rows[0].touched))
+		for _, column := range rows[0].columns {
+			sets = append(sets, fmt.Sprintf("[[%s]] = excluded.[[%s]]", column, column))
+		}
+		for _, column := range rows[0].touched {
+			sets = append(sets, fmt.Sprintf("[[%s]] = {:now}", column))
+			params["now"] = types.NowDateTime().String()
+		}
+		if len(sets) > 0 {
+			query += " ON CONFLICT ([[id]]) DO UPDATE SET " + strings.Join(sets, ", ")
+		} else {
+			query += " ON CONFLICT ([[id]]) DO NOTHING"
+		}
+	}
+
+	_, err := app.NonconcurrentDB().NewQuery(query).Bind(params).Execute()
+	return err
+}
+
+// applyFastPragmas disables the sqlite synchronous writes for the duration
+// of a fast import, trading durability on power loss for speed. The
+// returned function restores the previous setting.
+func applyFastPragmas(app core.App) (func(), error) {
+	var synchronous int
+	if err := app.NonconcurrentDB().NewQuery("PRAGMA synchronous").Row(&synchronous); err != nil {
+		return nil, err
+	}
+	if _, err := app.NonconcurrentDB().NewQuery("PRAGMA synchronous = OFF").Execute(); err != nil {
+		return nil, err
+	}
+	return func() {
+		app.NonconcurrentDB().NewQuery(fmt.Sprintf("PRAGMA synchronous = %d", synchronous)).Execute()
+	}, nil
+}
@@ -1,15 +1,16 @@
 package import_export
 
 import (
+	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core"
-	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbuilds/import_export/flags"
 	"github.com/spf13/cobra"
 )
 
@@ -17,20 +18,56 @@ func (p *Plugin) ImportRecordsCommand(app core.App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "records",
 		Short:   "import records from csv files in the records directory",
+		Long:    "import records from csv files in the records directory, or from stdin with \"-\" for a single collection",
 		Aliases: []string{"record", "rec", "r"},
-		Args:    cobra.ExactArgs(0),
+		Args:    stdioArgs,
 	}
 
 	collectionNames := []string{}
+	matchOnValues := []string{}
 	var noDelete bool
+	var skipManifest bool
+	var strictSchema bool
+	var yes bool
+	var mappingPath string
+	var continueOnError bool
+	var fast bool
+	var fastPragmas bool
+	var newIds bool
+	var profile string
+	batchSize := 500
+	mode := flags.NewRadioValue(importModeReplace, importModeSync)
+	var maxDeleteValue string
+	var dryRun bool
+	hooks := flags.NewRadioValue(hooksAll, hooksModelOnly, hooksNone)
+	errorsDir := filepath.Join(app.DataDir(), "import_errors")
 
 	cmd.Flags().StringVar(&p.RecordsDir, "records_dir", p.RecordsDir, "Path to directory for records csv files")
+	cmd.Flags().StringVar(&profile, "profile", profile, "Profile of records directories to import, see profiles list")
 	cmd.Flags().BoolVar(&p.AutoBackup, "auto_backup", p.AutoBackup, "Make an automatic database backup before the import")
 	cmd.Flags().StringSliceVar(&collectionNames, "collection", collectionNames, "Collections to inlcude in the import, otherwise imports all")
 	cmd.Flags().Var(p.OverrideVerified, "override_verified", "Determines override value of verfied state for auth records")
 	cmd.Flags().Var(p.OverrideEmailVisibility, "override_email_visibility", "Determines override value of email visibility for auth records")
 	cmd.Flags().BoolVar(&p.NoValidate, "no_validate", p.NoValidate, "Determines if record imports should skip validation")
+	cmd.Flags().BoolVar(&p.PreserveAutodate, "preserve_autodate", p.PreserveAutodate, "Determines if the imported created and updated values are preserved")
 	cmd.Flags().BoolVar(&noDelete, "no_delete", noDelete, "Determines if existing records should not be deleted")
+	cmd.Flags().Var(mode, "mode", "Import mode: replace (delete existing records first) or sync (upsert and delete the records missing from the files)")
+	cmd.Flags().StringVar(&maxDeleteValue, "max_delete", maxDeleteValue, "Maximum number (e.g. 100) or percentage (e.g. 5%) of records --mode sync may delete per collection")
+	cmd.Flags().BoolVar(&dryRun, "dry_run", dryRun, "Report the records --mode sync would delete without changing the database")
+	cmd.Flags().BoolVar(&skipManifest, "skip_manifest", skipManifest, "Skip verifying the files against the export manifest")
+	cmd.Flags().BoolVar(&strictSchema, "strict_schema", strictSchema, "Abort instead of warn when a collection schema changed since the export")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", yes, "Skip the confirmation prompt (required to import from stdin)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", mappingPath, "Path to a toml file mapping source columns to collection fields")
+	cmd.Flags().BoolVar(&continueOnError, "continue_on_error", continueOnError, "Continue importing on record errors and write an error report with the rejected records")
+	cmd.Flags().StringVar(&errorsDir, "errors_dir", errorsDir, "Path to directory for the error reports of --continue_on_error")
+	cmd.Flags().BoolVar(&fast, "fast", fast, "Insert records in batches inside a transaction, skipping the app hooks")
+	cmd.Flags().IntVar(&batchSize, "batch_size", batchSize, "Number of records per insert statement of --fast")
+	cmd.Flags().StringSliceVar(&matchOnValues, "match_on", matchOnValues, "Match existing records by a unique field instead of the id, e.g. users:email,categories:slug")
+	cmd.Flags().BoolVar(&newIds, "new_ids", newIds, "Assign new ids to the imported records, rewriting the relations between them")
+	cmd.Flags().Var(hooks, "hooks", "Hooks that fire when saving the records: all, model_only (field interceptors only) or none")
+	cmd.Flags().BoolVar(&fastPragmas, "fast_pragmas", fastPragmas, "Relax the sqlite durability pragmas during a --fast import")
+
+	p.registerScopeFlags(cmd)
 
 	for _, opt := range p.RecordsEncoding.Options() {
 		cmd.Flags().VarPF(p.RecordsEncoding, opt, "", fmt.Sprintf("%s encoding", opt)).NoOptDefVal = opt
@@ -43,14 +80,145 @@ func (p *Plugin) ImportRecordsCommand(app core.App) *cobra.Command {
 			return err
 		}
 
+		if err := mode.Validate(); err != nil {
+			return fmt.Errorf("mode: %w", err)
+		}
+		if mode.String() == importModeSync && noDelete {
+			return fmt.Errorf("--no_delete cannot be combined with --mode sync")
+		}
+		if mode.String() == importModeSync && newIds {
+			return fmt.Errorf("--new_ids cannot be combined with --mode sync")
+		}
+		if dryRun && mode.String() != importModeSync {
+			return fmt.Errorf("--dry_run requires --mode sync")
+		}
+		maxDelete, err := parseDeleteLimit(maxDeleteValue)
+		if err != nil {
+			return err
+		}
+
+		if err := hooks.Validate(); err != nil {
+			return fmt.Errorf("hooks: %w", err)
+		}
+		if fast && hooks.String() == hooksAll {
+			if cmd.Flags().Changed("hooks") {
+				return fmt.Errorf("--fast cannot fire the app hooks, use --hooks model_only or none")
+			}
+			hooks.Set(hooksModelOnly)
+		}
+
 		decoder, ok := handlers[p.RecordsEncoding.String()].(RecordsHandler)
 		if !ok {
 			return ErrNoRecordsHandler
 		}
 
-		fmt.Printf("Set to encoding: %s\n", p.RecordsEncoding)
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.RecordsEncoding)
+
+		mapping := recordsMapping{}
+		if mappingPath != "" {
+			var err error
+			if mapping, err = readRecordsMapping(mappingPath); err != nil {
+				return err
+			}
+		}
+
+		matchOn, err := parseMatchOn(app, matchOnValues)
+		if err != nil {
+			return err
+		}
+
+		useStdin := len(args) == 1
+		if useStdin && profile != "" {
+			return fmt.Errorf("--profile cannot be combined with stdin")
+		}
+
+		var files []*recordsFile
+		if useStdin {
+			if len(collectionNames) != 1 {
+				return fmt.Errorf("exactly one --collection is required to import from stdin")
+			}
+			if !yes {
+				return fmt.Errorf("--yes is required to import from stdin")
+			}
+			files = []*recordsFile{{Path: "-", Collection: collectionNames[0]}}
+		} else {
+			dirs := []string{p.RecordsDir}
+			if profile != "" {
+				if dirs, err = p.profileDirs(profile); err != nil {
+					return err
+				}
+			}
+
+			for i, dir := range dirs {
+				if _, err := os.Stat(dir); err != nil {
+					return err
+				}
+
+				skip, err := p.otherProfileDirs(dir)
+				if err != nil {
+					return err
+				}
+				dirFiles, err := findRecordsFiles(dir, decoder.FileExtension(), skip)
+				if err != nil {
+					return err
+				}
+				if len(collectionNames) > 0 {
+					dirFiles = slices.DeleteFunc(dirFiles, func(f *recordsFile) bool {
+						return !slices.Contains(collectionNames, f.Collection)
+					})
+				}
+				dirFiles = slices.DeleteFunc(dirFiles, func(f *recordsFile) bool {
+					collection, err := app.FindCachedCollectionByNameOrId(f.Collection)
+					if err != nil || p.includesRecordsOf(collection) {
+						return false
+					}
+					fmt.Fprintf(os.Stderr, "Skipping %s of system collection %s, use --system (and --include_oauth2 for oauth2 account links) to import it.\n", f.Path, f.Collection)
+					return true
+				})
+				for _, f := range dirFiles {
+					f.Overlay = i > 0
+				}
+
+				if !skipManifest {
+					if err := verifyRecordsManifest(app, dir, dirFiles, strictSchema); err != nil {
+						return err
+					}
+				}
+
+				files = append(files, dirFiles...)
+			}
+
+			// import the related collections first so relations resolve
+			if files, err = sortRecordsFilesByRelations(app, files); err != nil {
+				return err
+			}
+		}
+
+		importer := &recordsImporter{
+			p:             p,
+			app:           app,
+			ctx:           withImport(cmd.Context()),
+			decoder:       decoder,
+			mapping:       mapping,
+			hooks:         hooks.String(),
+			matchOn:       matchOn,
+			matchKeyCache: map[string]map[string]string{},
+			newIds:        newIds,
+			idMap:         map[string]map[string]string{},
+			refs:          map[string]map[string]*recordRef{},
+			mode:          mode.String(),
+			noDelete:      noDelete,
+			maxDelete:     maxDelete,
+			dryRun:        dryRun,
+			fast:          fast,
+			batchSize:     batchSize,
+		}
+		if continueOnError {
+			importer.report = newImportReport()
+		}
 
-		if _, err := os.Stat(p.RecordsDir); err != nil {
+		// fail before the backup and any deletes
+		if err := importer.checkFiles(files); err != nil {
 			return err
 		}
 
@@ -74,97 +242,75 @@ func (p *Plugin) ImportRecordsCommand(app core.App) *cobra.Command {
 			}, "\n")
 		}
 
-		if !noDelete {
+		if profile != "" {
+			msg += fmt.Sprintf("\nProfile: %s (%s)", profile, strings.Join(p.Profiles[profile], ", "))
+		}
+
+		if mode.String() == importModeSync {
+			msg += "\nWarning this will delete the current records missing from the data files!"
+		} else if !noDelete {
 			msg += "\nWarning this will delete all current records in these collections!"
 		}
 
-		if yes := confirm(msg, false); !yes {
-			fmt.Println("The command has been cancelled.")
+		if !yes && !confirm(msg, false) {
+			fmt.Fprintln(os.Stderr, "The command has been cancelled.")
 			return nil
 		}
 
-		if p.AutoBackup {
+		if p.AutoBackup && !dryRun {
 			name := backupName("import_records")
-			fmt.Printf("Making backup %s\n", name)
+			fmt.Fprintf(os.Stderr, "Making backup %s\n", name)
 			err := app.CreateBackup(cmd.Context(), name)
 			if err != nil {
 				return err
 			}
 		}
 
-		return filepath.Walk(p.RecordsDir, func(path string, info fs.FileInfo, err error) error {
-			if err != nil || info.IsDir() || filepath.Ext(path) != fmt.Sprintf(".%s", decoder.FileExtension()) {
-				return err
-			}
-
-			collectionName := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
-
-			if len(collectionNames) != 0 && !slices.Contains(collectionNames, collectionName) {
-				return nil
-			}
-
-			collection, err := app.FindCollectionByNameOrId(collectionName)
+		if fast && fastPragmas {
+			restore, err := applyFastPragmas(app)
 			if err != nil {
 				return err
 			}
+			defer restore()
+		}
 
-			if !noDelete {
-				if _, err := app.DB().Delete(collectionName, nil).Execute(); err != nil {
+		if err := importer.remapFiles(files); err != nil {
+			return err
+		}
+
+		if mode.String() == importModeSync {
+			// roll back everything if the sync would delete too much
+			err = app.RunInTransaction(func(txApp core.App) error {
+				importer.app = txApp
+				if err := importer.importFiles(files); err != nil {
 					return err
 				}
+				if dryRun {
+					return errDryRun
+				}
+				return nil
+			})
+			importer.app = app
+			if errors.Is(err, errDryRun) {
+				fmt.Fprintln(os.Stderr, "Dry run, the database is unchanged.")
+				err = nil
 			}
+		} else {
+			err = importer.importFiles(files)
+		}
+		if err != nil {
+			return err
+		}
 
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
-
-			records, err := decoder.DecodeRecords(collection, file)
-			if err != nil {
+		if report := importer.report; report != nil && len(report.errors) > 0 {
+			dir := filepath.Join(errorsDir, time.Now().UTC().Format(deltaTimeFormat))
+			if err := report.write(dir, decoder); err != nil {
 				return err
 			}
+			return fmt.Errorf("%d error(s) during the import, see %s", len(report.errors), dir)
+		}
 
-			fmt.Printf(
-				"Importing %d to collection %s.\n",
-				len(records),
-				collection.Name,
-			)
-
-			for _, record := range records {
-				record.MarkAsNew()
-				if record.Id == "" {
-					record.Id, err = security.RandomStringByRegex(`[a-z0-9]{15}`)
-					if err != nil {
-						return err
-					}
-				}
-				if record.Collection().IsAuth() {
-					record.Set(core.FieldNamePassword, security.RandomString(30))
-					record.RefreshTokenKey()
-					if raw, ok := record.GetRaw(core.FieldNamePassword).(*core.PasswordFieldValue); ok {
-						raw.Plain = ""
-					}
-					if verified, ok := p.OverrideVerified.GetValue(); ok {
-						record.SetVerified(verified)
-					}
-					if visibility, ok := p.OverrideEmailVisibility.GetValue(); ok {
-						record.SetEmailVisibility(visibility)
-					}
-				}
-				if p.NoValidate {
-					if err := app.SaveNoValidate(record); err != nil {
-						return err
-					}
-				} else {
-					if err := app.Save(record); err != nil {
-						return err
-					}
-				}
-			}
-
-			return nil
-		})
+		return nil
 	}
 
 	return cmd
@@ -0,0 +1,122 @@
+package import_export
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+)
+
+const (
+	// Existing records are deleted before the import.
+	importModeReplace = "replace"
+	// Records are upserted and only the existing records missing from the
+	// import are deleted.
+	importModeSync = "sync"
+)
+
+// deleteLimit is the maximum number of records a sync import may delete
+// from a collection, either as a count or as a percentage of the existing
+// records.
+type deleteLimit struct {
+	value   float64
+	percent bool
+}
+
+// parseDeleteLimit parses a limit like "100" or "5%". An empty value
+// results in a nil limit, which means no limit.
+func parseDeleteLimit(value string) (*deleteLimit, error) {
+	if value == "" {
+		return nil, nil
+	}
+	number, percent := strings.CutSuffix(value, "%")
+	limit, err := strconv.ParseFloat(number, 64)
+	if err != nil || limit < 0 || (percent && limit > 100) {
+		return nil, fmt.Errorf("invalid max_delete %q, expected a count or a percentage like 5%%", value)
+	}
+	return &deleteLimit{value: limit, percent: percent}, nil
+}
+
+// exceeded reports whether deleting records out of total existing records
+// exceeds the limit.
+func (l *deleteLimit) exceeded(deleting, total int) bool {
+	if l == nil {
+		return false
+	}
+	if l.percent {
+		return float64(deleting)*100 > l.value*float64(total)
+	}
+	return float64(deleting) > l.value
+}
+
+// syncDelete deletes the existing records of the collection whose ids are
+// not in keep, unless their number exceeds the limit. total is the number
+// of records before the import, so the new records don't dilute a
+// percentage limit.
+func (im *recordsImporter) syncDelete(collection *core.Collection, keep map[string]bool, total int, limit *deleteLimit) error {
+	ids := []string{}
+	if err := im.app.DB().Select("id").From(collection.Name).Column(&ids); err != nil {
+		return err
+	}
+
+	missing := []any{}
+	for _, id := range ids {
+		if !keep[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if limit.exceeded(len(missing), total) {
+		return fmt.Errorf(
+			"sync would delete %d of %d records from collection %s, which exceeds --max_delete",
+			len(missing),
+			total,
+			collection.Name,
+		)
+	}
+
+	if im.dryRun {
+		fmt.Fprintf(
+			os.Stderr,
+			"Would delete %d records missing from the import from collection %s.\n",
+			len(missing),
+			collection.Name,
+		)
+		return nil
+	}
+
+	for chunk := range slices.Chunk(missing, 500) {
+		if im.hooks != hooksAll {
+			if _, err := im.app.DB().Delete(collection.Name, dbx.In("id", chunk...)).Execute(); err != nil {
+				return err
+			}
+			continue
+		}
+		records, err := im.app.FindRecordsByIds(collection, cast.ToStringSlice(chunk))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := im.app.DeleteWithContext(im.ctx, record); err != nil {
+				return fmt.Errorf("failed to delete record %s: %w", record.Id, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(
+		os.Stderr,
+		"Deleted %d records missing from the import from collection %s.\n",
+		len(missing),
+		collection.Name,
+	)
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package import_export
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestImportRecordsSync(t *testing.T) {
+	const existing = `[
+		{"id":"a00000000000001","title":"new"},
+		{"id":"a00000000000002","title":"new"}
+	]`
+	const withNew = `[
+		{"id":"a00000000000001","title":"new"},
+		{"id":"a00000000000002","title":"new"},
+		{"id":"b00000000000001","title":"new"},
+		{"id":"b00000000000002","title":"new"},
+		{"id":"b00000000000003","title":"new"},
+		{"id":"b00000000000004","title":"new"},
+		{"id":"b00000000000005","title":"new"},
+		{"id":"b00000000000006","title":"new"}
+	]`
+	before := []string{"a00000000000001", "a00000000000002", "a00000000000003", "a00000000000004"}
+
+	scenarios := []struct {
+		name          string
+		file          string
+		args          []string
+		expectedError string
+		expectedIds   []string
+		expectedTitle string
+	}{
+		{
+			"deletes the missing records",
+			existing,
+			nil,
+			"",
+			[]string{"a00000000000001", "a00000000000002"},
+			"new",
+		},
+		{
+			"max_delete count not exceeded",
+			existing,
+			[]string{"--max_delete", "2"},
+			"",
+			[]string{"a00000000000001", "a00000000000002"},
+			"new",
+		},
+		{
+			"max_delete count exceeded",
+			existing,
+			[]string{"--max_delete", "1"},
+			"sync would delete 2 of 4 records from collection posts",
+			before,
+			"old",
+		},
+		{
+			"max_delete percentage not exceeded",
+			withNew,
+			[]string{"--max_delete", "50%"},
+			"",
+			[]string{
+				"a00000000000001", "a00000000000002",
+				"b00000000000001", "b00000000000002", "b00000000000003",
+				"b00000000000004", "b00000000000005", "b00000000000006",
+			},
+			"new",
+		},
+		{
+			// the new records don't count, 2 of 4 is over 40%
+			"max_delete percentage exceeded",
+			withNew,
+			[]string{"--max_delete", "40%"},
+			"sync would delete 2 of 4 records from collection posts",
+			before,
+			"old",
+		},
+		{
+			"dry run",
+			withNew,
+			[]string{"--dry_run"},
+			"",
+			before,
+			"old",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			collection := saveTestCollection(t, app, "posts", &core.TextField{Name: "title"})
+			for _, id := range before {
+				record := core.NewRecord(collection)
+				record.Id = id
+				record.Set("title", "old")
+				if err := app.Save(record); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dir := t.TempDir()
+			writeTestFile(t, dir, "posts.json", s.file)
+
+			cmd := newTestPlugin(t, app).ImportRecordsCommand(app)
+			cmd.SetArgs(append([]string{"--records_dir", dir, "--json", "--mode", "sync", "--yes"}, s.args...))
+			cmd.SilenceUsage = true
+			err := cmd.Execute()
+			if s.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), s.expectedError) {
+					t.Fatalf("Expected error %q, got %v", s.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := app.FindAllRecords(collection)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, record := range records {
+				ids = append(ids, record.Id)
+				if title := record.GetString("title"); title != s.expectedTitle {
+					t.Errorf("Expected the title of %s to be %q, got %q", record.Id, s.expectedTitle, title)
+				}
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, s.expectedIds) {
+				t.Errorf("Expected ids %q, got %q", s.expectedIds, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package import_export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+	"github.com/pocketbuilds/import_export/flags"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+)
+
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+
+	lintFormatText   = "text"
+	lintFormatJSON   = "json"
+	lintFormatGithub = "github"
+)
+
+// Same as the collection name rule of PocketBase.
+var collectionNameRegex = regexp.MustCompile(`^\w+$`)
+
+// Matches plain index columns, as opposed to expressions.
+var indexColumnRegex = regexp.MustCompile(`^\w+$`)
+
+var collectionTypes = []string{core.CollectionTypeBase, core.CollectionTypeAuth, core.CollectionTypeView}
+
+// lintIssue is a problem found in a collection file.
+type lintIssue struct {
+	File       string `json:"file"`
+	Collection string `json:"collection,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+}
+
+// lintedCollection is a decoded collection file.
+type lintedCollection struct {
+	path string
+	data map[string]any
+}
+
+// collectionsLinter checks the decoded collection files, without the
+// database.
+type collectionsLinter struct {
+	issues []*lintIssue
+	// sensitive holds the names of the non-auth collections whose records
+	// should not be public.
+	sensitive []string
+	// targets holds the ids and names of all collections of the files.
+	targets map[string]bool
+}
+
+func (p *Plugin) LintCollectionsCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "collections",
+		Short:   "check the collection files of the collections directory without the database",
+		Long:    "check the collection files of the collections directory without the database, exiting with status 1 if there are errors",
+		Aliases: []string{"collection", "col", "c"},
+		Args:    cobra.ExactArgs(0),
+	}
+
+	cmd.Flags().StringVar(&p.CollectionsDir, "collections_dir", p.CollectionsDir, "Path to directory for collections schema json files")
+
+	for _, opt := range p.CollectionsEncoding.Options() {
+		cmd.Flags().VarPF(p.CollectionsEncoding, opt, "", fmt.Sprintf("%s encoding", opt)).NoOptDefVal = opt
+	}
+	cmd.MarkFlagsMutuallyExclusive(p.CollectionsEncoding.Options()...)
+
+	format := flags.NewRadioValue(lintFormatText, lintFormatJSON, lintFormatGithub)
+	cmd.Flags().Var(format, "format", "Output format: text, json or github (workflow command annotations)")
+
+	sensitive := []string{}
+	cmd.Flags().StringSliceVar(&sensitive, "sensitive", sensitive, "Collections to warn about public list and view rules for, like auth collections")
+
+	var strict bool
+	cmd.Flags().BoolVar(&strict, "strict", strict, "Exit with status 1 on warnings too")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// validate manually to catch changes to config due to cli flags
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if err := format.Validate(); err != nil {
+			return fmt.Errorf("format: %w", err)
+		}
+
+		decoder, ok := handlers[p.CollectionsEncoding.String()].(CollectionHandler)
+		if !ok {
+			return ErrNoCollectionHandler
+		}
+
+		fmt.Fprintf(os.Stderr, "Set to encoding: %s\n", p.CollectionsEncoding)
+
+		l := &collectionsLinter{sensitive: sensitive, targets: map[string]bool{}}
+
+		files := 0
+		collections := []*lintedCollection{}
+		err := walkCollectionFiles(p.CollectionsDir, decoder, func(path string, data map[string]any, err error) error {
+			files++
+			if err == nil {
+				// plain json values, as encodings differ in their list types
+				var raw []byte
+				if raw, err = json.Marshal(data); err == nil {
+					data = map[string]any{}
+					err = json.Unmarshal(raw, &data)
+				}
+			}
+			if err != nil {
+				l.add(lintError, path, "", "", "cannot decode the file: %v", err)
+				return nil
+			}
+			collections = append(collections, &lintedCollection{path: path, data: data})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		l.lint(collections)
+
+		errorCount, warningCount := 0, 0
+		for _, issue := range l.issues {
+			if issue.Severity == lintError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+
+		if err := l.write(os.Stdout, format.String()); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(
+			os.Stderr,
+			"%d error(s) and %d warning(s) in %d collection file(s).\n",
+			errorCount,
+			warningCount,
+			files,
+		)
+		if errorCount > 0 || (strict && warningCount > 0) {
+			return errLintFailed
+		}
+		return nil
+	}
+
+	return cmd
+}
+
+func (l *collectionsLinter) add(severity, file, collection, field, format string, args ...any) {
+	l.issues = append(l.issues, &lintIssue{
+		File:       file,
+		Collection: collection,
+		Field:      field,
+		Severity:   severity,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// lint checks the collections, first across the files and then each on its
+// own.
+func (l *collectionsLinter) lint(collections []*lintedCollection) {
+	names := map[string]string{}
+	ids := map[string]string{}
+	for _, c := range collections {
+		name, id := cast.ToString(c.data["name"]), cast.ToString(c.data["id"])
+		if name != "" {
+			if other, ok := names[strings.ToLower(name)]; ok {
+				l.add(lintError, c.path, name, "", "duplicate collection name, also used by %s", other)
+			}
+			names[strings.ToLower(name)] = c.path
+			l.targets[name] = true
+		}
+		if id != "" {
+			if other, ok := ids[id]; ok {
+				l.add(lintError, c.path, name, "", "duplicate collection id %q, also used by %s", id, other)
+			}
+			ids[id] = c.path
+			l.targets[id] = true
+		}
+	}
+
+	indexes := map[string]string{}
+	for _, c := range collections {
+		l.lintCollection(c, indexes)
+	}
+}
+
+func (l *collectionsLinter) lintCollection(c *lintedCollection, indexes map[string]string) {
+	name, _ := c.data["name"].(string)
+	if name == "" {
+		l.add(lintError, c.path, "", "", "missing required key \"name\"")
+	} else if !collectionNameRegex.MatchString(name) {
+		l.add(lintError, c.path, name, "", "invalid collection name, only letters, digits and underscores are allowed")
+	}
+
+	collectionType, _ := c.data["type"].(string)
+	if !slices.Contains(collectionTypes, collectionType) {
+		l.add(lintError, c.path, name, "", "invalid collection type %q, expected one of %s", collectionType, strings.Join(collectionTypes, ", "))
+	}
+
+	fieldNames := []string{core.FieldNameId}
+	if collectionType == core.CollectionTypeView {
+		if query, _ := c.data["viewQuery"].(string); strings.TrimSpace(query) == "" {
+			l.add(lintError, c.path, name, "", "missing required key \"viewQuery\" of view collections")
+		}
+	} else if fields, ok := c.data["fields"]; !ok {
+		l.add(lintError, c.path, name, "", "missing required key \"fields\"")
+	} else if list, ok := fields.([]any); !ok {
+		l.add(lintError, c.path, name, "", "\"fields\" must be a list")
+	} else {
+		fieldNames = append(fieldNames, l.lintFields(c, name, list)...)
+	}
+
+	l.lintIndexes(c, name, fieldNames, indexes)
+	l.lintRules(c, name, collectionType)
+}
+
+// lintFields checks the fields of the collection and returns their names.
+func (l *collectionsLinter) lintFields(c *lintedCollection, collection string, fields []any) []string {
+	names := []string{}
+	ids := map[string]bool{}
+
+	for i, value := range fields {
+		data, ok := value.(map[string]any)
+		if !ok {
+			l.add(lintError, c.path, collection, "", "field %d must be an object", i+1)
+			continue
+		}
+
+		name, _ := data["name"].(string)
+		label := name
+		if name == "" {
+			label = fmt.Sprintf("#%d", i+1)
+			l.add(lintError, c.path, collection, label, "missing required key \"name\"")
+		} else if slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) }) {
+			l.add(lintError, c.path, collection, label, "duplicate field name")
+		} else {
+			names = append(names, name)
+		}
+
+		if id := cast.ToString(data["id"]); id != "" {
+			if ids[id] {
+				l.add(lintError, c.path, collection, label, "duplicate field id %q", id)
+			}
+			ids[id] = true
+		}
+
+		fieldType, _ := data["type"].(string)
+		factory, ok := core.Fields[fieldType]
+		if !ok {
+			l.add(lintError, c.path, collection, label, "unknown field type %q", fieldType)
+			continue
+		}
+
+		raw, err := json.Marshal(data)
+		if err == nil {
+			err = json.Unmarshal(raw, factory())
+		}
+		if err != nil {
+			l.add(lintError, c.path, collection, label, "invalid field options: %v", err)
+			continue
+		}
+
+		if fieldType == core.FieldTypeRelation {
+			target := cast.ToString(data["collectionId"])
+			if target == "" {
+				l.add(lintError, c.path, collection, label, "missing required key \"collectionId\" of relation fields")
+			} else if !l.targets[target] {
+				l.add(lintError, c.path, collection, label, "related collection %q is not in the collection files", target)
+			}
+		}
+	}
+
+	return names
+}
+
+// lintIndexes checks the index SQL of the collection and that the index
+// names are unique across the collections.
+func (l *collectionsLinter) lintIndexes(c *lintedCollection, collection string, fieldNames []string, indexes map[string]string) {
+	value, ok := c.data["indexes"]
+	if !ok || value == nil {
+		return
+	}
+	list, ok := value.([]any)
+	if !ok {
+		l.add(lintError, c.path, collection, "", "\"indexes\" must be a list")
+		return
+	}
+
+	for _, value := range list {
+		sql, _ := value.(string)
+		index := dbutils.ParseIndex(sql)
+		if !index.IsValid() {
+			l.add(lintError, c.path, collection, "", "invalid index SQL %q", sql)
+			continue
+		}
+		if !strings.EqualFold(index.TableName, collection) {
+			l.add(lintError, c.path, collection, "", "index %s is on table %q instead of the collection", index.IndexName, index.TableName)
+		}
+		if other, ok := indexes[strings.ToLower(index.IndexName)]; ok {
+			l.add(lintError, c.path, collection, "", "duplicate index name %s, also used by collection %s", index.IndexName, other)
+		}
+		indexes[strings.ToLower(index.IndexName)] = collection
+
+		for _, column := range index.Columns {
+			if !indexColumnRegex.MatchString(column.Name) {
+				continue // expression
+			}
+			if !slices.ContainsFunc(fieldNames, func(n string) bool { return strings.EqualFold(n, column.Name) }) {
+				l.add(lintError, c.path, collection, "", "index %s refers to unknown field %q", index.IndexName, column.Name)
+			}
+		}
+	}
+}
+
+// lintRules checks the types of the API rules and warns about public rules
+// that expose or modify the records of any user.
+func (l *collectionsLinter) lintRules(c *lintedCollection, collection, collectionType string) {
+	rules := []string{"listRule", "viewRule"}
+	if collectionType != core.CollectionTypeView {
+		rules = append(rules, "createRule", "updateRule", "deleteRule")
+	}
+	if collectionType == core.CollectionTypeAuth {
+		rules = append(rules, "authRule", "manageRule")
+	}
+
+	sensitive := collectionType == core.CollectionTypeAuth || slices.Contains(l.sensitive, collection)
+
+	for _, rule := range rules {
+		value, ok := c.data[rule]
+		if !ok || value == nil {
+			continue // only superusers
+		}
+		text, ok := value.(string)
+		if !ok {
+			l.add(lintError, c.path, collection, "", "%s must be a string or null", rule)
+			continue
+		}
+		if strings.TrimSpace(text) != "" {
+			continue
+		}
+		switch rule {
+		case "updateRule", "deleteRule", "manageRule":
+			l.add(lintWarning, c.path, collection, "", "%s is empty, so anyone can %s the records", rule, strings.TrimSuffix(rule, "Rule"))
+		case "listRule", "viewRule":
+			if sensitive {
+				l.add(lintWarning, c.path, collection, "", "%s is empty, so anyone can %s the records", rule, strings.TrimSuffix(rule, "Rule"))
+			}
+		}
+	}
+}
+
+// write writes the issues in the format.
+func (l *collectionsLinter) write(w io.Writer, format string) error {
+	switch format {
+	case lintFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		issues := l.issues
+		if issues == nil {
+			issues = []*lintIssue{}
+		}
+		return encoder.Encode(map[string]any{"issues": issues})
+	case lintFormatGithub:
+		for _, issue := range l.issues {
+			properties := "file=" + githubEscapeProperty(issue.File)
+			if location := issue.location(); location != "" {
+				properties += ",title=" + githubEscapeProperty(location)
+			}
+			fmt.Fprintf(w, "::%s %s::%s\n", issue.Severity, properties, githubEscapeData(issue.Message))
+		}
+	default:
+		for _, issue := range l.issues {
+			if location := issue.location(); location != "" {
+				fmt.Fprintf(w, "%s: %s: %s: %s\n", issue.File, issue.Severity, location, issue.Message)
+			} else {
+				fmt.Fprintf(w, "%s: %s: %s\n", issue.File, issue.Severity, issue.Message)
+			}
+		}
+	}
+	return nil
+}
+
+// location returns the collection and field of the issue, e.g. posts.title.
+func (i *lintIssue) location() string {
+	if i.Field != "" {
+		return i.Collection + "." + i.Field
+	}
+	return i.Collection
+}
+
+// githubEscapeData escapes the message of a GitHub workflow command.
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubEscapeProperty escapes a property value of a GitHub workflow command.
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
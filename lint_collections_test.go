@@ -0,0 +1,151 @@
+package import_export
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCollectionsLinter(t *testing.T) {
+	scenarios := []struct {
+		name      string
+		files     []string
+		sensitive []string
+		expected  []string
+	}{
+		{
+			"valid collections",
+			[]string{
+				`{"id":"pbc_1","name":"authors","type":"base","fields":[{"name":"name","type":"text"}],"indexes":["CREATE INDEX idx_name ON authors (name)"]}`,
+				`{"id":"pbc_2","name":"posts","type":"base","fields":[{"name":"author","type":"relation","collectionId":"authors"}],"listRule":""}`,
+				`{"id":"pbc_3","name":"stats","type":"view","viewQuery":"SELECT id FROM posts"}`,
+			},
+			nil,
+			[]string{},
+		},
+		{
+			"duplicate collections",
+			[]string{
+				`{"id":"pbc_1","name":"posts","type":"base","fields":[]}`,
+				`{"id":"pbc_1","name":"Posts","type":"base","fields":[]}`,
+			},
+			nil,
+			[]string{
+				"error 2.json Posts: duplicate collection name, also used by 1.json",
+				`error 2.json Posts: duplicate collection id "pbc_1", also used by 1.json`,
+			},
+		},
+		{
+			"invalid collection keys",
+			[]string{
+				`{"name":"my posts","type":"table","fields":[]}`,
+				`{"type":"base"}`,
+				`{"name":"stats","type":"view"}`,
+				`{"name":"tags","type":"base","fields":{}}`,
+			},
+			nil,
+			[]string{
+				"error 1.json my posts: invalid collection name, only letters, digits and underscores are allowed",
+				`error 1.json my posts: invalid collection type "table", expected one of base, auth, view`,
+				`error 2.json : missing required key "name"`,
+				`error 2.json : missing required key "fields"`,
+				`error 3.json stats: missing required key "viewQuery" of view collections`,
+				`error 4.json tags: "fields" must be a list`,
+			},
+		},
+		{
+			"invalid fields",
+			[]string{
+				`{"name":"posts","type":"base","fields":[
+					{"id":"f1","name":"title","type":"text"},
+					{"id":"f1","name":"Title","type":"text"},
+					{"name":"body","type":"markdown"},
+					{"type":"text"},
+					{"name":"views","type":"number","min":"a lot"},
+					{"name":"author","type":"relation","collectionId":"users"},
+					{"name":"tags","type":"relation"},
+					"status"
+				]}`,
+			},
+			nil,
+			[]string{
+				"error 1.json posts.Title: duplicate field name",
+				`error 1.json posts.Title: duplicate field id "f1"`,
+				`error 1.json posts.body: unknown field type "markdown"`,
+				`error 1.json posts.#4: missing required key "name"`,
+				"error 1.json posts.views: invalid field options: json: cannot unmarshal string into Go struct field NumberField.min of type float64",
+				`error 1.json posts.author: related collection "users" is not in the collection files`,
+				`error 1.json posts.tags: missing required key "collectionId" of relation fields`,
+				"error 1.json posts: field 8 must be an object",
+			},
+		},
+		{
+			"invalid indexes",
+			[]string{
+				`{"name":"posts","type":"base","fields":[{"name":"title","type":"text"}],"indexes":[
+					"CREATE INDEX idx_title ON posts (title)",
+					"CREATE INDEX idx_slug ON posts (slug)",
+					"CREATE INDEX idx_other ON tags (title)",
+					"CREATE INDEX idx_lower ON posts (lower(title))",
+					"not an index"
+				]}`,
+				`{"name":"tags","type":"base","fields":[],"indexes":["CREATE INDEX idx_title ON tags (id)"]}`,
+				`{"name":"users","type":"base","fields":[],"indexes":"idx"}`,
+			},
+			nil,
+			[]string{
+				`error 1.json posts: index idx_slug refers to unknown field "slug"`,
+				`error 1.json posts: index idx_other is on table "tags" instead of the collection`,
+				`error 1.json posts: invalid index SQL "not an index"`,
+				"error 2.json tags: duplicate index name idx_title, also used by collection posts",
+				`error 3.json users: "indexes" must be a list`,
+			},
+		},
+		{
+			"rules",
+			[]string{
+				`{"name":"posts","type":"base","fields":[],"listRule":"","viewRule":"","createRule":"","updateRule":"","deleteRule":null}`,
+				`{"name":"users","type":"auth","fields":[],"listRule":"","viewRule":"id = @request.auth.id","manageRule":""}`,
+				`{"name":"orders","type":"base","fields":[],"listRule":"","viewRule":1}`,
+				`{"name":"stats","type":"view","viewQuery":"SELECT 1 as id","listRule":"","updateRule":""}`,
+			},
+			[]string{"orders"},
+			[]string{
+				"warning 1.json posts: updateRule is empty, so anyone can update the records",
+				"warning 2.json users: listRule is empty, so anyone can list the records",
+				"warning 2.json users: manageRule is empty, so anyone can manage the records",
+				"warning 3.json orders: listRule is empty, so anyone can list the records",
+				"error 3.json orders: viewRule must be a string or null",
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			collections := []*lintedCollection{}
+			for i, file := range s.files {
+				data := map[string]any{}
+				if err := json.Unmarshal([]byte(file), &data); err != nil {
+					t.Fatal(err)
+				}
+				collections = append(collections, &lintedCollection{path: fmt.Sprintf("%d.json", i+1), data: data})
+			}
+
+			l := &collectionsLinter{sensitive: s.sensitive, targets: map[string]bool{}}
+			l.lint(collections)
+
+			issues := []string{}
+			for _, issue := range l.issues {
+				location := issue.Collection
+				if issue.Field != "" {
+					location += "." + issue.Field
+				}
+				issues = append(issues, fmt.Sprintf("%s %s %s: %s", issue.Severity, issue.File, location, issue.Message))
+			}
+			if !slices.Equal(issues, s.expected) {
+				t.Errorf("Expected issues\n%q\ngot\n%q", s.expected, issues)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package import_export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Name of the file describing the contents of an export directory.
+const manifestFilename = "manifest.json"
+
+type manifest struct {
+	PluginVersion     string          `json:"plugin_version"`
+	PocketBaseVersion string          `json:"pocketbase_version"`
+	Updated           types.DateTime  `json:"updated"`
+	Files             []*manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	// Path of the file relative to the export directory, slash separated.
+	Path           string `json:"path"`
+	CollectionId   string `json:"collection_id"`
+	CollectionName string `json:"collection_name"`
+	Rows           int    `json:"rows"`
+	SHA256         string `json:"sha256"`
+	Handler        string `json:"handler"`
+	// Fingerprint of the collection schema at the time of the export.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// readManifest reads the manifest from dir. A missing manifest file
+// results in a nil manifest.
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readOrNewManifest reads the manifest from dir, or returns a new empty
+// manifest if there is none.
+func readOrNewManifest(dir string) (*manifest, error) {
+	m, err := readManifest(dir)
+	if err != nil || m != nil {
+		return m, err
+	}
+	return newManifest(), nil
+}
+
+// newManifest returns a new empty manifest.
+func newManifest() *manifest {
+	return &manifest{Files: []*manifestFile{}}
+}
+
+// write prunes the entries of files that no longer exist and writes the
+// manifest to dir.
+func (m *manifest) write(dir string) error {
+	m.Files = slices.DeleteFunc(m.Files, func(f *manifestFile) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		return err != nil
+	})
+	slices.SortFunc(m.Files, func(a, b *manifestFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	m.PluginVersion = version
+	m.PocketBaseVersion = pocketbase.Version
+	m.Updated = types.NowDateTime()
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, manifestFilename), data)
+}
+
+// set adds the file entry to the manifest, replacing any entry with the
+// same path.
+func (m *manifest) set(file *manifestFile) {
+	m.Files = slices.DeleteFunc(m.Files, func(f *manifestFile) bool {
+		return f.Path == file.Path
+	})
+	m.Files = append(m.Files, file)
+}
+
+// get returns the entry of the file with the relative path, if any.
+func (m *manifest) get(path string) *manifestFile {
+	path = filepath.ToSlash(path)
+	for _, f := range m.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// collectionFingerprint returns a hash of the collection schema, used to
+// detect schema changes between an export and an import.
+func collectionFingerprint(collection *core.Collection) (string, error) {
+	data, err := json.Marshal(map[string]any{
+		"type":   collection.Type,
+		"fields": collection.Fields,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fileChecksum returns the hex encoded sha256 checksum of the file.
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyRecordsManifest verifies the records files against the manifest in
+// dir, if there is one. A checksum mismatch always results in an error,
+// while a changed collection schema only results in an error if strict.
+func verifyRecordsManifest(app core.App, dir string, files []*recordsFile, strict bool) error {
+	m, err := readManifest(dir)
+	if err != nil || m == nil {
+		return err
+	}
+
+	warned := map[string]bool{}
+
+	for _, f := range files {
+		rel, err := filepath.Rel(dir, f.Path)
+		if err != nil {
+			return err
+		}
+
+		entry := m.get(rel)
+		if entry == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s is not listed in the manifest.\n", rel)
+			continue
+		}
+
+		sum, err := fileChecksum(f.Path)
+		if err != nil {
+			return err
+		}
+		if sum != entry.SHA256 {
+			return fmt.Errorf("checksum of %s does not match the manifest, use --skip_manifest to import anyway", rel)
+		}
+
+		collection, err := app.FindCollectionByNameOrId(f.Collection)
+		if err != nil {
+			continue // reported by the import itself
+		}
+		fingerprint, err := collectionFingerprint(collection)
+		if err != nil {
+			return err
+		}
+		if fingerprint != entry.Fingerprint && !warned[collection.Id] {
+			if strict {
+				return fmt.Errorf("schema of collection %s changed since the export of %s", collection.Name, rel)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: schema of collection %s changed since the export of %s.\n", collection.Name, rel)
+			warned[collection.Id] = true
+		}
+	}
+
+	return nil
+}
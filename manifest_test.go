@@ -0,0 +1,213 @@
+package import_export
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestManifestWrite(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "posts.json", "[]")
+	writeTestFile(t, dir, "tags/tags_0001.json", "[]")
+
+	m := newManifest()
+	m.set(&manifestFile{Path: "tags/tags_0001.json", Rows: 1})
+	m.set(&manifestFile{Path: "posts.json", Rows: 1})
+	m.set(&manifestFile{Path: "deleted.json", Rows: 1})
+	m.set(&manifestFile{Path: "posts.json", Rows: 2})
+	if err := m.write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written == nil {
+		t.Fatal("Expected a manifest, got nil")
+	}
+
+	// the entries of missing files are pruned and the rest sorted by path
+	paths := []string{}
+	for _, f := range written.Files {
+		paths = append(paths, f.Path)
+	}
+	if expected := []string{"posts.json", "tags/tags_0001.json"}; !slices.Equal(paths, expected) {
+		t.Errorf("Expected files %q, got %q", expected, paths)
+	}
+	if entry := written.get(filepath.Join("tags", "tags_0001.json")); entry == nil {
+		t.Error("Expected the entry of the file in the subdirectory, got nil")
+	}
+	if entry := written.get("posts.json"); entry == nil || entry.Rows != 2 {
+		t.Errorf("Expected the replaced entry with 2 rows, got %+v", entry)
+	}
+	if written.PocketBaseVersion != pocketbase.Version || written.Updated.IsZero() {
+		t.Errorf("Expected the versions and the update time, got %+v", written)
+	}
+}
+
+// addTestBodyField changes the schema of the posts collection.
+func addTestBodyField(t *testing.T, app core.App) {
+	t.Helper()
+	collection, err := app.FindCollectionByNameOrId("posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection.Fields.Add(&core.TextField{Name: "body"})
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyRecordsManifest(t *testing.T) {
+	scenarios := []struct {
+		name string
+		// tamper changes the export before the verification
+		tamper        func(t *testing.T, app core.App, dir string)
+		strict        bool
+		expectedError string
+	}{
+		{
+			"unchanged",
+			func(t *testing.T, app core.App, dir string) {},
+			true,
+			"",
+		},
+		{
+			"no manifest",
+			func(t *testing.T, app core.App, dir string) {
+				writeTestFile(t, dir, "posts.json", `[{"title":"changed"}]`)
+				if err := os.Remove(filepath.Join(dir, manifestFilename)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			true,
+			"",
+		},
+		{
+			"checksum mismatch",
+			func(t *testing.T, app core.App, dir string) {
+				writeTestFile(t, dir, "posts.json", `[{"title":"changed"}]`)
+			},
+			false,
+			"checksum of posts.json does not match the manifest",
+		},
+		{
+			"schema changed",
+			func(t *testing.T, app core.App, dir string) {
+				addTestBodyField(t, app)
+			},
+			false,
+			"",
+		},
+		{
+			"schema changed in strict mode",
+			func(t *testing.T, app core.App, dir string) {
+				addTestBodyField(t, app)
+			},
+			true,
+			"schema of collection posts changed since the export of posts.json",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			collection := saveTestCollection(t, app, "posts", &core.TextField{Name: "title"})
+			record := core.NewRecord(collection)
+			record.Set("title", "Hello")
+			if err := app.Save(record); err != nil {
+				t.Fatal(err)
+			}
+
+			// export like export records
+			dir := t.TempDir()
+			path := filepath.Join(dir, "posts.json")
+			encoder := handlers["json"].(RecordsHandler)
+			checksum, err := writeRecordsFile(path, encoder, []*core.Record{record})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fingerprint, err := collectionFingerprint(collection)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := newManifest()
+			m.set(&manifestFile{
+				Path:           "posts.json",
+				CollectionId:   collection.Id,
+				CollectionName: collection.Name,
+				Rows:           1,
+				SHA256:         checksum,
+				Handler:        encoder.FileExtension(),
+				Fingerprint:    fingerprint,
+			})
+			if err := m.write(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			s.tamper(t, app, dir)
+
+			files := []*recordsFile{{Path: path, Collection: collection.Name}}
+			err = verifyRecordsManifest(app, dir, files, s.strict)
+			if s.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), s.expectedError) {
+				t.Fatalf("Expected error %q, got %v", s.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestImportRecordsChecksumMismatch(t *testing.T) {
+	app := newTestApp(t)
+	collection := saveTestCollection(t, app, "posts", &core.TextField{Name: "title"})
+	existing := core.NewRecord(collection)
+	existing.Set("title", "Existing")
+	if err := app.Save(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "posts.json", `[{"title":"Hello"}]`)
+	m := newManifest()
+	m.set(&manifestFile{Path: "posts.json", CollectionName: "posts", SHA256: "0000"})
+	if err := m.write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newTestPlugin(t, app).ImportRecordsCommand(app)
+	cmd.SetArgs([]string{"--records_dir", dir, "--json", "--yes"})
+	cmd.SilenceUsage = true
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "checksum of posts.json does not match the manifest") {
+		t.Fatalf("Expected a checksum error, got %v", err)
+	}
+	if _, err := app.FindRecordById(collection, existing.Id); err != nil {
+		t.Errorf("Expected the existing record to be kept, got %v", err)
+	}
+
+	cmd = newTestPlugin(t, app).ImportRecordsCommand(app)
+	cmd.SetArgs([]string{"--records_dir", dir, "--json", "--yes", "--skip_manifest"})
+	cmd.SilenceUsage = true
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := app.FindAllRecords(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].GetString("title") != "Hello" {
+		t.Errorf("Expected only the imported record, got %d records", len(records))
+	}
+}
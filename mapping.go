@@ -0,0 +1,110 @@
+package import_export
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Columns of exported records data that are not collection fields and are
+// ignored on import without a warning.
+var recordMetaColumns = []string{"collectionId", "collectionName", "expand"}
+
+// recordsMapping maps the columns of foreign records data files to
+// collection fields, keyed by collection name.
+type recordsMapping map[string]*collectionMapping
+
+type collectionMapping struct {
+	// Columns maps source column names to field names. Mapping a column to
+	// an empty field name ignores it.
+	Columns map[string]string `toml:"columns"`
+	// Constants are field values set on every record, replacing any source
+	// value.
+	Constants map[string]any `toml:"constants"`
+	// Defaults are field values set on the records where the source value
+	// is missing or empty.
+	Defaults map[string]any `toml:"defaults"`
+}
+
+// readRecordsMapping reads the toml mapping file at path.
+func readRecordsMapping(path string) (recordsMapping, error) {
+	mapping := recordsMapping{}
+	if _, err := toml.DecodeFile(path, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+	return mapping, nil
+}
+
+// validate checks that all mapping targets are fields of the collection.
+func (m *collectionMapping) validate(collection *core.Collection) error {
+	invalid := []string{}
+	for _, name := range m.Columns {
+		if name != "" && collection.Fields.GetByName(name) == nil {
+			invalid = append(invalid, name)
+		}
+	}
+	for name := range m.Constants {
+		if collection.Fields.GetByName(name) == nil {
+			invalid = append(invalid, name)
+		}
+	}
+	for name := range m.Defaults {
+		if collection.Fields.GetByName(name) == nil {
+			invalid = append(invalid, name)
+		}
+	}
+	if len(invalid) > 0 {
+		slices.Sort(invalid)
+		return fmt.Errorf(
+			"mapping for collection %s has unknown field(s): %s",
+			collection.Name,
+			strings.Join(slices.Compact(invalid), ", "),
+		)
+	}
+	return nil
+}
+
+// apply returns the row with the columns renamed to their mapped fields and
+// the constants and defaults applied.
+func (m *collectionMapping) apply(row map[string]any) map[string]any {
+	result := make(map[string]any, len(row))
+	for column, value := range row {
+		name, ok := m.Columns[column]
+		if !ok {
+			name = column
+		}
+		if name != "" {
+			result[name] = value
+		}
+	}
+	for name, value := range m.Defaults {
+		if v, ok := result[name]; !ok || v == nil || v == "" {
+			result[name] = value
+		}
+	}
+	for name, value := range m.Constants {
+		result[name] = value
+	}
+	return result
+}
+
+// unknownColumns returns the sorted columns of the rows that are not
+// fields of the collection.
+func unknownColumns(collection *core.Collection, rows []map[string]any) []string {
+	unknown := []string{}
+	for _, row := range rows {
+		for column := range row {
+			if collection.Fields.GetByName(column) != nil ||
+				slices.Contains(recordMetaColumns, column) ||
+				slices.Contains(unknown, column) {
+				continue
+			}
+			unknown = append(unknown, column)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
@@ -0,0 +1,157 @@
+package import_export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestCheckFiles(t *testing.T) {
+	type file struct {
+		collection string
+		name       string
+		content    string
+	}
+
+	scenarios := []struct {
+		name             string
+		mapping          recordsMapping
+		files            []file
+		continueOnError  bool
+		expectedProblems []string
+	}{
+		{
+			"valid",
+			recordsMapping{"posts": {Columns: map[string]string{"Headline": "title"}}},
+			[]file{
+				{"authors", "authors.json", `[{"name":"Alice","collectionId":"x"}]`},
+				{"posts", "posts.json", `[{"Headline":"Hello"}]`},
+			},
+			false,
+			nil,
+		},
+		{
+			"unknown columns of unmapped collections",
+			nil,
+			[]file{{"authors", "authors.json", `[{"name":"Alice","nickname":"Al"}]`}},
+			false,
+			nil,
+		},
+		{
+			"unmapped columns of all files",
+			recordsMapping{
+				"authors": {Columns: map[string]string{"Name": "name"}},
+				"posts":   {Columns: map[string]string{"Headline": "title"}},
+			},
+			[]file{
+				{"authors", "authors.json", `[{"Name":"Alice","nickname":"Al"}]`},
+				{"posts", "posts.json", `[{"Headline":"Hello","Body":"..."}]`},
+			},
+			false,
+			[]string{
+				"unmapped column(s) for collection authors in {dir}/authors.json: nickname",
+				"unmapped column(s) for collection posts in {dir}/posts.json: Body",
+			},
+		},
+		{
+			"mapping sections without files",
+			recordsMapping{
+				"authors": {Columns: map[string]string{"Name": "fullname"}},
+				"missing": {Columns: map[string]string{"Name": "name"}},
+			},
+			[]file{{"posts", "posts.json", `[{"title":"Hello"}]`}},
+			false,
+			[]string{
+				"mapping for collection authors has unknown field(s): fullname",
+				"mapping for unknown collection missing",
+			},
+		},
+		{
+			"undecodable file",
+			nil,
+			[]file{{"posts", "posts.json", `[{"title":`}},
+			false,
+			[]string{"{dir}/posts.json: unexpected EOF"},
+		},
+		{
+			"undecodable file continuing on error",
+			nil,
+			[]file{{"posts", "posts.json", `[{"title":`}},
+			true,
+			nil,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			saveTestCollection(t, app, "authors", &core.TextField{Name: "name"})
+			saveTestCollection(t, app, "posts", &core.TextField{Name: "title"})
+
+			dir := t.TempDir()
+			files := []*recordsFile{}
+			for _, f := range s.files {
+				path := writeTestFile(t, dir, f.name, f.content)
+				files = append(files, &recordsFile{Path: path, Collection: f.collection})
+			}
+
+			im := newTestImporter(t, app)
+			im.mapping = s.mapping
+			if s.continueOnError {
+				im.report = newImportReport()
+			}
+
+			err := im.checkFiles(files)
+			if len(s.expectedProblems) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			for _, problem := range s.expectedProblems {
+				problem = strings.ReplaceAll(problem, "{dir}", dir)
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("Expected the error to contain %q, got %q", problem, err)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRecordsChecksFilesUpFront(t *testing.T) {
+	app := newTestApp(t)
+	authors := saveTestCollection(t, app, "authors", &core.TextField{Name: "name"})
+	saveTestCollection(t, app, "posts", &core.TextField{Name: "title"})
+
+	existing := core.NewRecord(authors)
+	existing.Set("name", "Existing")
+	if err := app.Save(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "authors.json", `[{"name":"Alice"}]`)
+	writeTestFile(t, dir, "posts.json", `[{"Headline":"Hello","Body":"..."}]`)
+	mappingPath := writeTestFile(t, t.TempDir(), "mapping.toml", "[posts.columns]\nHeadline = \"title\"\n")
+
+	cmd := newTestPlugin(t, app).ImportRecordsCommand(app)
+	cmd.SetArgs([]string{"--records_dir", dir, "--json", "--mapping", mappingPath, "--yes"})
+	cmd.SilenceUsage = true
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "unmapped column(s) for collection posts") {
+		t.Fatalf("Expected an unmapped column error, got %v", err)
+	}
+
+	// the authors, which are imported before the posts, are left untouched
+	records, err := app.FindAllRecords(authors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Id != existing.Id {
+		t.Errorf("Expected only the existing author, got %d records", len(records))
+	}
+}
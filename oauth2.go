@@ -0,0 +1,81 @@
+package import_export
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// The oauth2 config of the collection files is ignored.
+	oauth2Skip = "skip"
+	// The oauth2 config of the collection files is applied, but the client
+	// secrets of the existing providers are kept.
+	oauth2Merge = "merge"
+	// The oauth2 config of the collection files replaces the existing one.
+	oauth2Replace = "replace"
+)
+
+// registerScopeFlags registers the flags of the config options that
+// determine which collections and collection options are included.
+func (p *Plugin) registerScopeFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&p.IncludeOauth2, "include_oauth2", p.IncludeOauth2, "Include the oauth2 config of auth collections and the records of the oauth2 account links")
+	cmd.Flags().BoolVar(&p.System, "system", p.System, "Include system collections")
+}
+
+// includesRecordsOf reports whether the records of the collection are
+// included in records imports and exports: system collections only with
+// the system option and the oauth2 account links additionally only with the
+// include_oauth2 option.
+func (p *Plugin) includesRecordsOf(collection *core.Collection) bool {
+	if collection.System && !p.System {
+		return false
+	}
+	return collection.Name != core.CollectionNameExternalAuths || p.IncludeOauth2
+}
+
+// mergeOAuth2 sets the client secrets of the oauth2 providers of the
+// decoded collections to the secrets of the existing providers with the
+// same name, so only the non-secret settings of the files are applied.
+// Existing providers missing from the files are kept.
+func mergeOAuth2(app core.App, collections []map[string]any) error {
+	for _, data := range collections {
+		oauth2, ok := data["oauth2"].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		existing, err := app.FindCollectionByNameOrId(cast.ToString(data["id"]))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		providers := []any{}
+		names := map[string]bool{}
+		for _, value := range cast.ToSlice(oauth2["providers"]) {
+			provider, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			name := cast.ToString(provider["name"])
+			names[name] = true
+			if current, ok := existing.OAuth2.GetProviderConfig(name); ok {
+				provider["clientSecret"] = current.ClientSecret
+			}
+			providers = append(providers, provider)
+		}
+		for _, current := range existing.OAuth2.Providers {
+			if !names[current.Name] {
+				providers = append(providers, current)
+			}
+		}
+		oauth2["providers"] = providers
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package import_export
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+)
+
+func TestMergeOAuth2(t *testing.T) {
+	app := newTestApp(t)
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users.OAuth2.Providers = []core.OAuth2ProviderConfig{
+		{Name: "google", ClientId: "google-id", ClientSecret: "google-secret"},
+		{Name: "github", ClientId: "github-id", ClientSecret: "github-secret"},
+	}
+	if err := app.Save(users); err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name              string
+		collection        map[string]any
+		expectedSecrets   map[string]string
+		expectedProviders []string
+	}{
+		{
+			"existing collection",
+			map[string]any{"id": users.Id, "oauth2": map[string]any{"providers": []any{
+				map[string]any{"name": "google", "clientId": "new-id", "clientSecret": "${GOOGLE_SECRET}"},
+				map[string]any{"name": "gitlab", "clientId": "gitlab-id", "clientSecret": "gitlab-secret"},
+			}}},
+			map[string]string{"google": "google-secret", "gitlab": "gitlab-secret", "github": "github-secret"},
+			[]string{"google", "gitlab", "github"},
+		},
+		{
+			"new collection",
+			map[string]any{"id": "pbc_new", "oauth2": map[string]any{"providers": []any{
+				map[string]any{"name": "google", "clientId": "new-id", "clientSecret": "new-secret"},
+			}}},
+			map[string]string{"google": "new-secret"},
+			[]string{"google"},
+		},
+		{
+			"without oauth2",
+			map[string]any{"id": users.Id},
+			map[string]string{},
+			[]string{},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := mergeOAuth2(app, []map[string]any{s.collection}); err != nil {
+				t.Fatal(err)
+			}
+
+			oauth2, _ := s.collection["oauth2"].(map[string]any)
+			providers := cast.ToSlice(oauth2["providers"])
+			if len(providers) != len(s.expectedProviders) {
+				t.Fatalf("Expected %d providers, got %v", len(s.expectedProviders), providers)
+			}
+			for i, value := range providers {
+				var name, secret string
+				switch provider := value.(type) {
+				case map[string]any:
+					name, secret = cast.ToString(provider["name"]), cast.ToString(provider["clientSecret"])
+				case core.OAuth2ProviderConfig:
+					name, secret = provider.Name, provider.ClientSecret
+				default:
+					t.Fatalf("Unexpected provider %T", value)
+				}
+				if name != s.expectedProviders[i] {
+					t.Errorf("Expected provider %d to be %q, got %q", i, s.expectedProviders[i], name)
+				}
+				if secret != s.expectedSecrets[name] {
+					t.Errorf("Expected the secret %q of provider %s, got %q", s.expectedSecrets[name], name, secret)
+				}
+			}
+		})
+	}
+}
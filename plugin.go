@@ -1,11 +1,14 @@
 package import_export
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
 	"github.com/pocketbuilds/import_export/flags"
 	import_export_csv "github.com/pocketbuilds/import_export/handlers/csv"
 	import_export_json "github.com/pocketbuilds/import_export/handlers/json"
@@ -16,6 +19,15 @@ import (
 )
 
 type Plugin struct {
+	// Anonymization strategies to apply to record exports, keyed by
+	// "collection.field".
+	//   - options: fake_email, hash, keep_domain, null, redact, shuffle
+	//   - flag: anonymize (applies the rules)
+	//   - default: {} (no rules)
+	Anonymize map[string]string `json:"anonymize"`
+	// Secret salt used by the hash based anonymization strategies.
+	//   - default: "" (no salt)
+	AnonymizeSalt string `json:"anonymize_salt"`
 	// Determines if an automatic database backup should be made prior to an import.
 	//   - flag: auto_backup
 	//   - default: true
@@ -32,8 +44,10 @@ type Plugin struct {
 	// Optional prefix to prepend the commands to avoid possible name collisions.
 	//   - default: "" (no prefix)
 	CommandPrefix string `json:"command_prefix"`
-	// Determines if to include oauth2 config in collections export.
-	//   - flag: TODO
+	// Determines if to include the oauth2 config of auth collections in collection
+	// imports and exports, and the records of the oauth2 account links
+	// (_externalAuths) in records imports and exports of system collections.
+	//   - flag: include_oauth2
 	//   - default: false
 	IncludeOauth2 bool `json:"include_oauth2"`
 	// Path to directory for records data files.
@@ -59,11 +73,22 @@ type Plugin struct {
 	//   - flag: override_email_visibility
 	//   - default: null
 	OverrideEmailVisibility *flags.OptionalBoolValue `json:"override_email_visibility"`
+	// Named records import profiles, each listing directories relative to
+	// the records directory, which are imported in order: the first as the
+	// base and the others as overlays upserted on top of it by id.
+	//   - flag: profile (selects the profile to import)
+	//   - default: {} (no profiles)
+	Profiles map[string][]string `json:"profiles"`
+	// Determines if the imported created and updated (autodate) values are
+	// preserved, instead of being set to the time of the import.
+	//   - flag: preserve_autodate
+	//   - default: false
+	PreserveAutodate bool `json:"preserve_autodate"`
 	// Determines if measures are taken to reduce git diff. Currently, just sets
 	// updated to the zero datetime.
 	//   - default: false
 	ReduceGitDiff bool `json:"reduce_git_diff"`
-	// Determines if to include system collections.
+	// Determines if to include system collections in imports and exports.
 	//   - flag: system
 	//   - default: false
 	System bool `json:"system"`
@@ -131,7 +156,9 @@ func (p *Plugin) PreValidate(app core.App) error {
 // Validate implements validation.Validatable.
 func (p *Plugin) Validate() error {
 	return validation.ValidateStruct(p,
+		validation.Field(&p.Anonymize, validation.By(validateAnonymizeRules)),
 		validation.Field(&p.CollectionsEncoding, validation.Required),
+		validation.Field(&p.Profiles, validation.By(p.validateProfiles)),
 		validation.Field(&p.RecordsEncoding, validation.Required),
 	)
 }
@@ -147,12 +174,53 @@ func (p *Plugin) Init(app core.App) error {
 			}
 			app.RootCmd.AddCommand(rootCmd)
 		}
+		diffCmd := p.DiffCommand(app)
+		lintCmd := p.LintCommand(app)
 		rootCmd.AddCommand(p.ImportCommand(app))
 		rootCmd.AddCommand(p.ExportCommand(app))
+		rootCmd.AddCommand(diffCmd)
+		rootCmd.AddCommand(lintCmd)
+		rootCmd.AddCommand(p.ProfilesCommand(app))
+
+		var failed bool
+		exitOnCheckFailure(diffCmd, &failed)
+		exitOnCheckFailure(lintCmd, &failed)
+		// the app ignores the errors of the commands, so exit with status 1
+		// after all the other terminate handlers ran
+		app.OnTerminate().Bind(&hook.Handler[*core.TerminateEvent]{
+			Func: func(e *core.TerminateEvent) error {
+				err := e.Next()
+				if failed {
+					os.Exit(1)
+				}
+				return err
+			},
+			Priority: -99999,
+		})
 	}
 	return nil
 }
 
+// exitOnCheckFailure wraps the commands of the tree to record whether a
+// check failed, i.e. returned errDiffFound or errLintFailed, which the
+// command reports in its output instead of as an error.
+func exitOnCheckFailure(cmd *cobra.Command, failed *bool) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			if errors.Is(err, errDiffFound) || errors.Is(err, errLintFailed) {
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				*failed = true
+			}
+			return err
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		exitOnCheckFailure(sub, failed)
+	}
+}
+
 func (p *Plugin) ImportCommand(app core.App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "import",
@@ -170,5 +238,25 @@ func (p *Plugin) ExportCommand(app core.App) *cobra.Command {
 	}
 	cmd.AddCommand(p.ExportRecordsCommand(app))
 	cmd.AddCommand(p.ExportCollectionsCommand(app))
+	cmd.AddCommand(p.ExportFakeCommand(app))
+	return cmd
+}
+
+func (p *Plugin) DiffCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare records or collection files with the database",
+	}
+	cmd.AddCommand(p.DiffRecordsCommand(app))
+	cmd.AddCommand(p.DiffCollectionsCommand(app))
+	return cmd
+}
+
+func (p *Plugin) LintCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check collection files without the database",
+	}
+	cmd.AddCommand(p.LintCollectionsCommand(app))
 	return cmd
 }
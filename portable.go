@@ -0,0 +1,148 @@
+package import_export
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+)
+
+// unknownCollectionError is the error of a relation field whose target
+// collection is neither decoded nor existing.
+type unknownCollectionError struct {
+	collection string
+	field      string
+	target     string
+}
+
+func (e *unknownCollectionError) Error() string {
+	return fmt.Sprintf("relation field %s.%s: unknown collection %q", e.collection, e.field, e.target)
+}
+
+// makePortable replaces the target collection ids of the relation fields of
+// the collection with the collection names, and clears the collection and
+// field ids if omitIds is set, so the collection can be imported into any
+// instance. names holds the collection names keyed by id.
+func makePortable(collection *core.Collection, names map[string]string, omitIds bool) error {
+	for _, field := range collection.Fields {
+		if relation, ok := field.(*core.RelationField); ok {
+			name, ok := names[relation.CollectionId]
+			if !ok {
+				return fmt.Errorf(
+					"relation field %s.%s: unknown collection %s",
+					collection.Name,
+					relation.Name,
+					relation.CollectionId,
+				)
+			}
+			relation.CollectionId = name
+		}
+		if omitIds {
+			field.SetId("")
+		}
+	}
+	if omitIds {
+		collection.Id = ""
+	}
+	return nil
+}
+
+// resolvePortable resolves the portable parts of the decoded collections in
+// place: collections without id get the id of the existing collection with
+// the same name, or a new id, relation targets given by name are replaced by
+// the collection ids, and fields without id get the id of the existing
+// field with the same name and type. Relation targets that are neither
+// decoded nor existing collections are left as they are and reported by the
+// returned error, joining an unknownCollectionError per field.
+func resolvePortable(app core.App, collections []map[string]any) error {
+	ids := map[string]bool{}
+	byName := map[string]string{}
+	existing := map[string]*core.Collection{}
+
+	for _, data := range collections {
+		name := cast.ToString(data["name"])
+		id := cast.ToString(data["id"])
+
+		var current *core.Collection
+		for _, identifier := range []string{id, name} {
+			if identifier == "" {
+				continue
+			}
+			found, err := app.FindCachedCollectionByNameOrId(identifier)
+			if err == nil {
+				current = found
+				break
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		if id == "" {
+			if current != nil {
+				id = current.Id
+			} else {
+				// the same default id PocketBase gives new collections
+				collectionType := cmp.Or(cast.ToString(data["type"]), core.CollectionTypeBase)
+				id = "pbc_" + strconv.Itoa(int(crc32.ChecksumIEEE([]byte(collectionType+name))))
+			}
+			data["id"] = id
+		}
+		if current != nil && current.Id == id {
+			existing[id] = current
+		}
+
+		ids[id] = true
+		byName[name] = id
+	}
+
+	unknown := []error{}
+	for _, data := range collections {
+		id := cast.ToString(data["id"])
+		for _, value := range cast.ToSlice(data["fields"]) {
+			field, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			fieldName, fieldType := cast.ToString(field["name"]), cast.ToString(field["type"])
+
+			if cast.ToString(field["id"]) == "" && existing[id] != nil {
+				if current := existing[id].Fields.GetByName(fieldName); current != nil && current.Type() == fieldType {
+					field["id"] = current.GetId()
+				}
+			}
+
+			if fieldType != core.FieldTypeRelation {
+				continue
+			}
+			target := cast.ToString(field["collectionId"])
+			switch {
+			case ids[target]:
+			case byName[target] != "":
+				target = byName[target]
+			default:
+				related, err := app.FindCachedCollectionByNameOrId(target)
+				if errors.Is(err, sql.ErrNoRows) {
+					unknown = append(unknown, &unknownCollectionError{
+						collection: cast.ToString(data["name"]),
+						field:      fieldName,
+						target:     target,
+					})
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				target = related.Id
+			}
+			field["collectionId"] = target
+		}
+	}
+
+	return errors.Join(unknown...)
+}
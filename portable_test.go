@@ -0,0 +1,155 @@
+package import_export
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+)
+
+func TestMakePortable(t *testing.T) {
+	scenarios := []struct {
+		name               string
+		target             string
+		omitIds            bool
+		expectedError      bool
+		expectedTarget     string
+		expectedEmptyIds   bool
+		expectedCollection string
+	}{
+		{"known target", "pbc_authors", false, false, "authors", false, "pbc_posts"},
+		{"known target omitting ids", "pbc_authors", true, false, "authors", true, ""},
+		{"unknown target", "pbc_missing", false, true, "", false, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			collection := core.NewBaseCollection("posts", "pbc_posts")
+			collection.Fields.Add(
+				&core.TextField{Id: "text1", Name: "title"},
+				&core.RelationField{Id: "relation1", Name: "author", CollectionId: s.target},
+			)
+
+			err := makePortable(collection, map[string]string{"pbc_authors": "authors"}, s.omitIds)
+			if hasErr := err != nil; hasErr != s.expectedError {
+				t.Fatalf("Expected hasErr %v, got %v", s.expectedError, err)
+			}
+			if s.expectedError {
+				return
+			}
+
+			if target := collection.Fields.GetByName("author").(*core.RelationField).CollectionId; target != s.expectedTarget {
+				t.Errorf("Expected target %q, got %q", s.expectedTarget, target)
+			}
+			if collection.Id != s.expectedCollection {
+				t.Errorf("Expected collection id %q, got %q", s.expectedCollection, collection.Id)
+			}
+			for _, field := range collection.Fields {
+				if emptyId := field.GetId() == ""; emptyId != s.expectedEmptyIds {
+					t.Errorf("Expected empty id %v for field %s, got %q", s.expectedEmptyIds, field.GetName(), field.GetId())
+				}
+			}
+		})
+	}
+}
+
+func TestResolvePortable(t *testing.T) {
+	app := newTestApp(t)
+	authors := saveTestCollection(t, app, "authors", &core.TextField{Name: "name"})
+	existingName := authors.Fields.GetByName("name")
+
+	scenarios := []struct {
+		name           string
+		collection     map[string]any
+		expectedId     string
+		expectedField  string
+		expectedTarget string
+		expectedError  bool
+	}{
+		{
+			"existing collection by name",
+			map[string]any{"name": "authors", "fields": []any{
+				map[string]any{"name": "name", "type": core.FieldTypeText},
+			}},
+			authors.Id,
+			existingName.GetId(),
+			"",
+			false,
+		},
+		{
+			"existing collection with a changed field type",
+			map[string]any{"name": "authors", "fields": []any{
+				map[string]any{"name": "name", "type": core.FieldTypeNumber},
+			}},
+			authors.Id,
+			"",
+			"",
+			false,
+		},
+		{
+			"new collection",
+			map[string]any{"name": "posts", "type": core.CollectionTypeBase, "fields": []any{
+				map[string]any{"name": "title", "type": core.FieldTypeText},
+			}},
+			core.NewBaseCollection("posts").Id,
+			"",
+			"",
+			false,
+		},
+		{
+			"relation to a decoded collection by name",
+			map[string]any{"name": "posts", "fields": []any{
+				map[string]any{"name": "next", "type": core.FieldTypeRelation, "collectionId": "posts"},
+			}},
+			core.NewBaseCollection("posts").Id,
+			"",
+			core.NewBaseCollection("posts").Id,
+			false,
+		},
+		{
+			"relation to an existing collection by name",
+			map[string]any{"name": "posts", "fields": []any{
+				map[string]any{"name": "author", "type": core.FieldTypeRelation, "collectionId": "authors"},
+			}},
+			core.NewBaseCollection("posts").Id,
+			"",
+			authors.Id,
+			false,
+		},
+		{
+			"relation to an unknown collection",
+			map[string]any{"name": "posts", "fields": []any{
+				map[string]any{"name": "author", "type": core.FieldTypeRelation, "collectionId": "missing"},
+			}},
+			core.NewBaseCollection("posts").Id,
+			"",
+			"missing",
+			true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := resolvePortable(app, []map[string]any{s.collection})
+			if hasErr := err != nil; hasErr != s.expectedError {
+				t.Fatalf("Expected hasErr %v, got %v", s.expectedError, err)
+			}
+			var unknownErr *unknownCollectionError
+			if s.expectedError && !errors.As(err, &unknownErr) {
+				t.Fatalf("Expected an unknown collection error, got %v", err)
+			}
+
+			if id := cast.ToString(s.collection["id"]); id != s.expectedId {
+				t.Errorf("Expected collection id %q, got %q", s.expectedId, id)
+			}
+			field := cast.ToSlice(s.collection["fields"])[0].(map[string]any)
+			if id := cast.ToString(field["id"]); id != s.expectedField {
+				t.Errorf("Expected field id %q, got %q", s.expectedField, id)
+			}
+			if target := cast.ToString(field["collectionId"]); target != s.expectedTarget {
+				t.Errorf("Expected relation target %q, got %q", s.expectedTarget, target)
+			}
+		})
+	}
+}
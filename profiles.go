@@ -0,0 +1,191 @@
+package import_export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// validateProfiles checks that every profile has at least one directory
+// and none of them is the records directory, whose files are separate from
+// the files of the profiles.
+func (p *Plugin) validateProfiles(value any) error {
+	profiles, _ := value.(map[string][]string)
+	for name, dirs := range profiles {
+		if len(dirs) == 0 || slices.Contains(dirs, "") {
+			return fmt.Errorf("profile %s must list one or more directories", name)
+		}
+		for _, dir := range dirs {
+			if p.isRecordsDir(dir) {
+				return fmt.Errorf("profile %s must not list the records directory %q", name, dir)
+			}
+		}
+	}
+	return nil
+}
+
+// isRecordsDir reports whether the profile directory resolves to the
+// records directory.
+func (p *Plugin) isRecordsDir(dir string) bool {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.RecordsDir, dir)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	recordsDir, err := filepath.Abs(p.RecordsDir)
+	return err == nil && dir == recordsDir
+}
+
+// profileDirs returns the directories of the profile in import order, with
+// relative paths resolved against the records directory.
+func (p *Plugin) profileDirs(name string) ([]string, error) {
+	dirs, ok := p.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	result := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(p.RecordsDir, dir)
+		}
+		result = append(result, dir)
+	}
+	return result, nil
+}
+
+// allProfileDirs returns the directories of all profiles.
+func (p *Plugin) allProfileDirs() ([]string, error) {
+	result := []string{}
+	for name := range p.Profiles {
+		dirs, err := p.profileDirs(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dirs...)
+	}
+	return result, nil
+}
+
+// otherProfileDirs returns the directories of all profiles except dir, to
+// leave out of the records files of dir.
+func (p *Plugin) otherProfileDirs(dir string) ([]string, error) {
+	dirs, err := p.allProfileDirs()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(dirs, func(d string) bool {
+		return d == filepath.Clean(dir)
+	}), nil
+}
+
+func (p *Plugin) ProfilesCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "Manage records import profiles",
+	}
+	cmd.AddCommand(p.ProfilesListCommand(app))
+	return cmd
+}
+
+func (p *Plugin) ProfilesListCommand(app core.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "list the records import profiles and the files of their directories",
+		Aliases: []string{"ls"},
+		Args:    cobra.MaximumNArgs(1),
+	}
+
+	cmd.Flags().StringVar(&p.RecordsDir, "records_dir", p.RecordsDir, "Path to directory for records csv files")
+
+	for _, opt := range p.RecordsEncoding.Options() {
+		cmd.Flags().VarPF(p.RecordsEncoding, opt, "", fmt.Sprintf("%s encoding", opt)).NoOptDefVal = opt
+	}
+	cmd.MarkFlagsMutuallyExclusive(p.RecordsEncoding.Options()...)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// validate manually to catch changes to config due to cli flags
+		if err := p.Validate(); err != nil {
+			return err
+		}
+
+		decoder, ok := handlers[p.RecordsEncoding.String()].(RecordsHandler)
+		if !ok {
+			return ErrNoRecordsHandler
+		}
+
+		names := []string{}
+		for name := range p.Profiles {
+			if len(args) == 0 || args[0] == name {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			if len(args) > 0 {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			fmt.Fprintln(os.Stderr, "No profiles configured.")
+			return nil
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			fmt.Println(name)
+			dirs, err := p.profileDirs(name)
+			if err != nil {
+				return err
+			}
+			for i, dir := range dirs {
+				layer := "base"
+				if i > 0 {
+					layer = "overlay"
+				}
+				fmt.Printf("  %s (%s)\n", dir, layer)
+
+				if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+					fmt.Println("    missing directory")
+					continue
+				}
+				skip, err := p.otherProfileDirs(dir)
+				if err != nil {
+					return err
+				}
+				files, err := findRecordsFiles(dir, decoder.FileExtension(), skip)
+				if err != nil {
+					return err
+				}
+				if len(files) == 0 {
+					fmt.Println("    no records files")
+					continue
+				}
+				m, err := readManifest(dir)
+				if err != nil {
+					return err
+				}
+				for _, f := range files {
+					rel, err := filepath.Rel(dir, f.Path)
+					if err != nil {
+						return err
+					}
+					rows := ""
+					if m != nil {
+						if entry := m.get(rel); entry != nil {
+							rows = fmt.Sprintf(", %d rows", entry.Rows)
+						}
+					}
+					fmt.Printf("    %s (%s%s)\n", rel, f.Collection, rows)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return cmd
+}
@@ -0,0 +1,41 @@
+package import_export
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProfiles(t *testing.T) {
+	recordsDir := filepath.Join(t.TempDir(), "records")
+
+	scenarios := []struct {
+		name          string
+		dirs          []string
+		expectedError string
+	}{
+		{"subdirectories", []string{"base", "demo"}, ""},
+		{"outside directory", []string{filepath.Join(filepath.Dir(recordsDir), "seed")}, ""},
+		{"no directories", []string{}, "must list one or more directories"},
+		{"empty directory", []string{"base", ""}, "must list one or more directories"},
+		{"records directory", []string{"."}, "must not list the records directory"},
+		{"records directory by path", []string{"demo/.."}, "must not list the records directory"},
+		{"absolute records directory", []string{recordsDir}, "must not list the records directory"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			p := &Plugin{RecordsDir: recordsDir}
+			err := p.validateProfiles(map[string][]string{"demo": s.dirs})
+			if s.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), s.expectedError) {
+				t.Fatalf("Expected error %q, got %v", s.expectedError, err)
+			}
+		})
+	}
+}
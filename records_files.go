@@ -0,0 +1,231 @@
+package import_export
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Time format used for the timestamp of incremental (delta) records files.
+const deltaTimeFormat = "20060102T150405"
+
+// recordsFile is a records data file found in the records directory.
+type recordsFile struct {
+	Path       string
+	Collection string
+	// Delta is the timestamp of an incremental export file, or empty for a
+	// full (base) export file.
+	Delta string
+	// Chunk is the 1-based index of the file within a chunked export, or 0
+	// if the export was not chunked.
+	Chunk int
+	// Overlay is set for the files of a profile overlay directory, which
+	// are applied on top of the records of the previous directories.
+	Overlay bool
+}
+
+// recordsFilename returns the records data file path, relative to the
+// records directory, e.g. posts.csv, posts.20261017T120000.delta.csv,
+// posts/0001.csv or posts/20261017T120000.delta.0001.csv.
+func recordsFilename(collection, delta string, chunk int, ext string) string {
+	switch {
+	case chunk > 0 && delta != "":
+		return filepath.Join(collection, fmt.Sprintf("%s.delta.%04d.%s", delta, chunk, ext))
+	case chunk > 0:
+		return filepath.Join(collection, fmt.Sprintf("%04d.%s", chunk, ext))
+	case delta != "":
+		return fmt.Sprintf("%s.%s.delta.%s", collection, delta, ext)
+	default:
+		return fmt.Sprintf("%s.%s", collection, ext)
+	}
+}
+
+// parseRecordsFilename is the inverse of recordsFilename, for the path
+// relative to the records directory. Chunk files are only recognized inside
+// a subdirectory, whose name is used as their collection name, so e.g.
+// 2024.csv is the file of a collection named 2024.
+func parseRecordsFilename(rel, ext string) (file *recordsFile, ok bool) {
+	if filepath.Base(rel) == manifestFilename {
+		return nil, false
+	}
+	stem, ok := strings.CutSuffix(filepath.Base(rel), "."+ext)
+	if !ok || stem == "" || strings.HasPrefix(stem, ".") {
+		return nil, false
+	}
+	parts := strings.Split(stem, ".")
+	file = &recordsFile{Path: rel}
+
+	// chunk files
+	chunk, err := strconv.Atoi(parts[len(parts)-1])
+	if parent := filepath.Dir(rel); parent != "." && err == nil && chunk > 0 {
+		file.Collection = filepath.Base(parent)
+		file.Chunk = chunk
+		switch {
+		case len(parts) == 1:
+			return file, true
+		case len(parts) == 3 && parts[1] == "delta" && isDeltaTime(parts[0]):
+			file.Delta = parts[0]
+			return file, true
+		default:
+			return nil, false
+		}
+	}
+
+	file.Collection = parts[0]
+	switch {
+	case len(parts) == 1:
+		return file, true
+	case len(parts) == 3 && parts[2] == "delta" && isDeltaTime(parts[1]):
+		file.Delta = parts[1]
+		return file, true
+	default:
+		return nil, false
+	}
+}
+
+// isRecordsExportFile reports whether the path, relative to the records
+// directory, is a records data file of any records handler.
+func isRecordsExportFile(rel string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(rel), ".")
+	if _, ok := handlers[ext].(RecordsHandler); !ok {
+		return false
+	}
+	file, ok := parseRecordsFilename(rel, ext)
+	return ok && (file.Chunk > 0) == (strings.Count(filepath.ToSlash(rel), "/") == 1)
+}
+
+func isDeltaTime(s string) bool {
+	_, err := time.Parse(deltaTimeFormat, s)
+	return err == nil
+}
+
+// findRecordsFiles returns the records data files in dir and its
+// subdirectories, except for the skip directories (e.g. of other profiles),
+// grouped by collection, with the base file(s) first and the delta files
+// following in the order they were exported. Chunks are ordered by their
+// index. Files with the extension that are not named like records files
+// result in an error, rather than being left out of an import.
+func findRecordsFiles(dir, ext string, skip []string) ([]*recordsFile, error) {
+	files := []*recordsFile{}
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && slices.Contains(skip, filepath.Clean(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != "."+ext || info.Name() == manifestFilename || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		file, ok := parseRecordsFilename(rel, ext)
+		if !ok {
+			return fmt.Errorf(
+				"%s is not named like a records file, e.g. <collection>.%s or <collection>/0001.%s",
+				path,
+				ext,
+				ext,
+			)
+		}
+		file.Path = path
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(files, func(a, b *recordsFile) int {
+		if c := strings.Compare(a.Collection, b.Collection); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Delta, b.Delta); c != 0 {
+			return c
+		}
+		return a.Chunk - b.Chunk
+	})
+	return files, nil
+}
+
+// exportedRecordsFiles returns the records data files of previous exports
+// of the export directory.
+func exportedRecordsFiles(dir *exportDir, ext string) []*recordsFile {
+	files := []*recordsFile{}
+	for _, rel := range dir.existing {
+		if file, ok := parseRecordsFilename(rel, ext); ok {
+			file.Path = filepath.Join(dir.dir, rel)
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// sortRecordsFilesByRelations orders the files so that the collections are
+// imported after the collections they relate to, where possible. The order
+// of the files of a collection is kept.
+func sortRecordsFilesByRelations(app core.App, files []*recordsFile) ([]*recordsFile, error) {
+	collections := []*core.Collection{}
+	byId := map[string][]*recordsFile{}
+	for _, f := range files {
+		collection, err := app.FindCollectionByNameOrId(f.Collection)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byId[collection.Id]; !ok {
+			collections = append(collections, collection)
+		}
+		byId[collection.Id] = append(byId[collection.Id], f)
+	}
+
+	sorted := make([]*recordsFile, 0, len(files))
+	for _, collection := range sortCollectionsByRelations(collections) {
+		sorted = append(sorted, byId[collection.Id]...)
+	}
+
+	return sorted, nil
+}
+
+// sortCollectionsByRelations orders the collections after the collections
+// of the slice they relate to. Relation cycles are broken by the slice
+// order.
+func sortCollectionsByRelations(collections []*core.Collection) []*core.Collection {
+	sorted := make([]*core.Collection, 0, len(collections))
+	visited := map[string]bool{}
+
+	var visit func(collection *core.Collection)
+	visit = func(collection *core.Collection) {
+		if visited[collection.Id] {
+			return
+		}
+		visited[collection.Id] = true
+		for _, field := range collection.Fields {
+			relation, ok := field.(*core.RelationField)
+			if !ok {
+				continue
+			}
+			for _, target := range collections {
+				if target.Id == relation.CollectionId {
+					visit(target)
+				}
+			}
+		}
+		sorted = append(sorted, collection)
+	}
+
+	for _, collection := range collections {
+		visit(collection)
+	}
+
+	return sorted
+}
@@ -0,0 +1,160 @@
+package import_export
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRecordsFilename(t *testing.T) {
+	scenarios := []struct {
+		rel        string
+		expectedOk bool
+		collection string
+		delta      string
+		chunk      int
+	}{
+		{"posts.csv", true, "posts", "", 0},
+		{"posts.20261017T120000.delta.csv", true, "posts", "20261017T120000", 0},
+		{"posts/0001.csv", true, "posts", "", 1},
+		{"posts/0012.csv", true, "posts", "", 12},
+		{"posts/20261017T120000.delta.0002.csv", true, "posts", "20261017T120000", 2},
+		{"seeds/posts.csv", true, "posts", "", 0},
+		{"seeds/posts/0001.csv", true, "posts", "", 1},
+		// numeric collection names outside of subdirectories
+		{"2024.csv", true, "2024", "", 0},
+		{"2024.20261017T120000.delta.csv", true, "2024", "20261017T120000", 0},
+		{"20261017T120000.delta.0001.csv", false, "", "", 0},
+		{"posts/0000.csv", true, "0000", "", 0},
+		{"posts.json", false, "", "", 0},
+		{"manifest.json", false, "", "", 0},
+		{".posts.csv", false, "", "", 0},
+		{"posts.backup.csv", false, "", "", 0},
+		{"posts.2026.delta.csv", false, "", "", 0},
+		{"posts/latest.delta.0001.csv", false, "", "", 0},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.rel, func(t *testing.T) {
+			file, ok := parseRecordsFilename(filepath.FromSlash(s.rel), "csv")
+			if ok != s.expectedOk {
+				t.Fatalf("Expected ok %v, got %v", s.expectedOk, ok)
+			}
+			if !ok {
+				return
+			}
+			if file.Collection != s.collection {
+				t.Errorf("Expected collection %q, got %q", s.collection, file.Collection)
+			}
+			if file.Delta != s.delta {
+				t.Errorf("Expected delta %q, got %q", s.delta, file.Delta)
+			}
+			if file.Chunk != s.chunk {
+				t.Errorf("Expected chunk %d, got %d", s.chunk, file.Chunk)
+			}
+		})
+	}
+}
+
+func TestRecordsFilenameRoundTrip(t *testing.T) {
+	scenarios := []struct {
+		collection string
+		delta      string
+		chunk      int
+	}{
+		{"posts", "", 0},
+		{"posts", "20261017T120000", 0},
+		{"posts", "", 3},
+		{"posts", "20261017T120000", 3},
+		{"2024", "", 0},
+	}
+
+	for _, s := range scenarios {
+		rel := recordsFilename(s.collection, s.delta, s.chunk, "csv")
+		file, ok := parseRecordsFilename(rel, "csv")
+		if !ok {
+			t.Errorf("%s: expected to parse", rel)
+			continue
+		}
+		if file.Collection != s.collection || file.Delta != s.delta || file.Chunk != s.chunk {
+			t.Errorf("%s: expected %s/%s/%d, got %s/%s/%d", rel, s.collection, s.delta, s.chunk, file.Collection, file.Delta, file.Chunk)
+		}
+		if !isRecordsExportFile(rel) {
+			t.Errorf("%s: expected to be an export file", rel)
+		}
+	}
+}
+
+func TestFindRecordsFiles(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		files         []string
+		skip          []string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			name:     "top level files",
+			files:    []string{"users.csv", "posts.csv", "posts.20261017T120000.delta.csv", "notes.txt"},
+			expected: []string{"posts.csv", "posts.20261017T120000.delta.csv", "users.csv"},
+		},
+		{
+			name:     "nested files",
+			files:    []string{"seeds/users.csv", "posts/0002.csv", "posts/0001.csv"},
+			expected: []string{"posts/0001.csv", "posts/0002.csv", "seeds/users.csv"},
+		},
+		{
+			name:     "skipped profile directories",
+			files:    []string{"users.csv", "demo/users.csv"},
+			skip:     []string{"demo"},
+			expected: []string{"users.csv"},
+		},
+		{
+			name:     "metadata and hidden files",
+			files:    []string{"users.csv", ".backup.csv", "manifest.csv"},
+			expected: []string{"manifest.csv", "users.csv"},
+		},
+		{
+			name:     "numeric collection names",
+			files:    []string{"2024.csv", "posts/0001.csv"},
+			expected: []string{"2024.csv", "posts/0001.csv"},
+		},
+		{
+			name:          "files not named like records files",
+			files:         []string{"users.csv", "users.backup.csv"},
+			expectedError: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range s.files {
+				writeTestFile(t, dir, name, "")
+			}
+			skip := make([]string, len(s.skip))
+			for i, d := range s.skip {
+				skip[i] = filepath.Join(dir, d)
+			}
+
+			files, err := findRecordsFiles(dir, "csv", skip)
+			if s.expectedError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(files) != len(s.expected) {
+				t.Fatalf("Expected %d files, got %d", len(s.expected), len(files))
+			}
+			for i, f := range files {
+				if expected := filepath.Join(dir, s.expected[i]); f.Path != expected {
+					t.Errorf("Expected file %d to be %s, got %s", i, expected, f.Path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,642 @@
+package import_export
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// recordsImporter holds the options and state of a records import run.
+type recordsImporter struct {
+	p       *Plugin
+	app     core.App
+	ctx     context.Context
+	decoder RecordsHandler
+	mapping recordsMapping
+	// report collects the failed records if the import continues on error,
+	// otherwise it is nil.
+	report *importReport
+	// matchOn holds the natural key fields of the match_on collections,
+	// keyed by collection name.
+	matchOn map[string]string
+	// matchKeyCache caches the ids of the existing records of the match_on
+	// collections, keyed by collection name and natural key.
+	matchKeyCache map[string]map[string]string
+	// newIds replaces the ids of the imported records with new ids.
+	newIds bool
+	// idMap maps the source ids of the imported records to the ids that
+	// replace them, matched or new, keyed by collection id.
+	idMap map[string]map[string]string
+	// refs holds the symbolic references of the records, keyed by
+	// collection id and reference name.
+	refs map[string]map[string]*recordRef
+	// mode is the import mode, one of importModeReplace or importModeSync.
+	mode string
+	// noDelete keeps the existing records in replace mode.
+	noDelete bool
+	// maxDelete limits the deletes of sync mode, nil means no limit.
+	maxDelete *deleteLimit
+	// dryRun reports the deletes of sync mode instead of making them, the
+	// caller rolls back the upserts.
+	dryRun bool
+	// hooks determines which hooks fire when saving the records, one of
+	// hooksAll, hooksModelOnly or hooksNone.
+	hooks string
+	// fast enables batched inserts that bypass the app hooks.
+	fast      bool
+	batchSize int
+	// columns holds the decoded columns of the records, so that upserts
+	// only update those of the existing records.
+	columns map[*core.Record][]string
+	// sourceRows holds the rows the records were decoded from, before any
+	// mapping or reference resolution, to write them to the rejects.
+	sourceRows map[*core.Record]map[string]any
+}
+
+const (
+	// All app hooks fire, as with a regular save.
+	hooksAll = "all"
+	// Only the field interceptors of the record model (autodate, password,
+	// etc.) run, without any app hooks.
+	hooksModelOnly = "model_only"
+	// Nothing but the validations runs, the records are inserted as decoded.
+	hooksNone = "none"
+)
+
+// decodeFile decodes the records data file, or stdin for the path
+// "-", applying the column mapping if there is one. Columns of stdin that
+// do not map to a collection field are reported before anything gets
+// imported, while those of the files are checked up front by checkFiles. If
+// report is not nil, records that fail to load are added to it and left
+// nil in the result.
+func (im *recordsImporter) decodeFile(collection *core.Collection, path string) ([]*core.Record, error) {
+	decoder, mapping, report := im.decoder, im.mapping[collection.Name], im.report
+
+	reader := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	rowsDecoder, ok := decoder.(RowsHandler)
+	if !ok {
+		if mapping != nil {
+			return nil, fmt.Errorf("the %s handler does not support column mappings", decoder.FileExtension())
+		}
+		if im.p.PreserveAutodate {
+			return nil, fmt.Errorf("the %s handler does not support preserve_autodate", decoder.FileExtension())
+		}
+		return decoder.DecodeRecords(collection, reader)
+	}
+
+	rows, err := rowsDecoder.DecodeRows(reader)
+	if err != nil {
+		return nil, err
+	}
+	sourceRows := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		sourceRows[i] = maps.Clone(row)
+	}
+
+	if mapping != nil {
+		if err := mapping.validate(collection); err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			rows[i] = mapping.apply(row)
+		}
+	}
+
+	refErrs, err := im.declareRefs(collection, path, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// the columns of the files are checked up front by checkFiles
+	if path == "-" {
+		if err := checkColumns(collection, path, rows, mapping != nil); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]*core.Record, 0, len(rows))
+	for i, row := range rows {
+		record := core.NewRecord(collection)
+		err := refErrs[i]
+		if err == nil {
+			err = im.resolveRefs(collection, row)
+		}
+		// load the row regardless of reference errors to report the record
+		if loadErr := rowsDecoder.LoadRow(record, row); err == nil {
+			err = loadErr
+		}
+		if err == nil && im.p.PreserveAutodate {
+			err = loadAutodates(record, row)
+		}
+		if err != nil {
+			if report == nil {
+				return nil, importRecordError(recordsSource(path), i+1, record, err)
+			}
+			report.add(collection, recordsSource(path), i+1, record, sourceRows[i], err)
+			record = nil
+		} else {
+			if im.columns == nil {
+				im.columns = map[*core.Record][]string{}
+				im.sourceRows = map[*core.Record]map[string]any{}
+			}
+			im.columns[record] = slices.Collect(maps.Keys(row))
+			im.sourceRows[record] = sourceRows[i]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// checkFiles checks the mapping and the files ahead of the import, before
+// anything is changed, and declares the references of the files, so rows
+// can refer to the rows of files imported after them and references that no
+// file declares can be reported. All mapping sections, including those of
+// collections without files, must map to fields of existing collections,
+// and the columns of the files of mapped collections to fields. The errors
+// of all files are returned at once. Files that fail to decode are left to
+// the import to report if it continues on error. Stdin is checked when it
+// is imported.
+func (im *recordsImporter) checkFiles(files []*recordsFile) error {
+	errs := []error{}
+
+	for _, name := range slices.Sorted(maps.Keys(im.mapping)) {
+		collection, err := im.app.FindCachedCollectionByNameOrId(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mapping for unknown collection %s", name))
+			continue
+		}
+		if err := im.mapping[name].validate(collection); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	rowsDecoder, ok := im.decoder.(RowsHandler)
+	if !ok {
+		// mappings and references require rows
+		return errors.Join(errs...)
+	}
+
+	for _, f := range files {
+		if f.Path == "-" {
+			continue
+		}
+		collection, err := im.app.FindCollectionByNameOrId(f.Collection)
+		if err != nil {
+			return err
+		}
+		rows, err := decodeRowsFile(rowsDecoder, f.Path)
+		if err != nil {
+			if im.report == nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+			}
+			continue
+		}
+		mapping := im.mapping[collection.Name]
+		if mapping != nil {
+			for i, row := range rows {
+				rows[i] = mapping.apply(row)
+			}
+		}
+		if err := checkColumns(collection, f.Path, rows, mapping != nil); err != nil {
+			errs = append(errs, err)
+		}
+		if _, err := im.declareRefs(collection, f.Path, rows); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkColumns reports the columns of the rows of the source that do not
+// map to a collection field: as an error for mapped collections, and as a
+// warning otherwise.
+func checkColumns(collection *core.Collection, path string, rows []map[string]any, mapped bool) error {
+	unknown := unknownColumns(collection, rows)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if mapped {
+		return fmt.Errorf(
+			"unmapped column(s) for collection %s in %s: %s",
+			collection.Name,
+			recordsSource(path),
+			strings.Join(unknown, ", "),
+		)
+	}
+	fmt.Fprintf(
+		os.Stderr,
+		"Warning: ignoring unknown column(s) for collection %s in %s: %s\n",
+		collection.Name,
+		recordsSource(path),
+		strings.Join(unknown, ", "),
+	)
+	return nil
+}
+
+// importFiles imports the files in order. In replace mode the existing
+// records of a collection are deleted before its first file is imported,
+// unless noDelete is set, while in sync mode all records are upserted and
+// the existing records missing from the files are deleted afterwards.
+func (im *recordsImporter) importFiles(files []*recordsFile) error {
+	sync := im.mode == importModeSync
+	deleted := map[string]bool{}
+	synced := []*core.Collection{}
+	keep := map[string]map[string]bool{}
+	// the number of records of the synced collections before the import
+	existing := map[string]int{}
+
+	for _, f := range files {
+		collection, err := im.app.FindCollectionByNameOrId(f.Collection)
+		if err != nil {
+			return err
+		}
+
+		// delta and overlay files, matched and synced records are applied
+		// on top of the existing records
+		_, matched := im.matchOn[collection.Name]
+		upsert := f.Delta != "" || f.Overlay || matched || sync
+
+		source := recordsSource(f.Path)
+
+		if sync && keep[collection.Id] == nil {
+			total, err := im.app.CountRecords(collection)
+			if err != nil {
+				return err
+			}
+			synced = append(synced, collection)
+			keep[collection.Id] = map[string]bool{}
+			existing[collection.Id] = int(total)
+		}
+
+		records, err := im.decodeFile(collection, f.Path)
+		if err != nil {
+			if im.report == nil {
+				return err
+			}
+			im.report.add(collection, source, 0, nil, nil, err)
+			continue
+		}
+
+		if err := im.remapRecords(collection, records); err != nil {
+			return err
+		}
+		if err := im.rewriteRelations(collection, records); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(
+			os.Stderr,
+			"Importing %d to collection %s from %s.\n",
+			len(records),
+			collection.Name,
+			source,
+		)
+
+		if !sync && !im.noDelete && !upsert && !deleted[collection.Id] {
+			if _, err := im.app.DB().Delete(collection.Name, nil).Execute(); err != nil {
+				return err
+			}
+			deleted[collection.Id] = true
+		}
+
+		if err := im.saveRecords(collection, records, source, upsert); err != nil {
+			return err
+		}
+
+		if sync {
+			// the ids of new records are generated on save
+			for _, record := range records {
+				if record != nil {
+					keep[collection.Id][record.Id] = true
+				}
+			}
+		}
+	}
+
+	// delete the dependent records first
+	for _, collection := range slices.Backward(synced) {
+		if im.report != nil && im.report.has(collection) {
+			fmt.Fprintf(
+				os.Stderr,
+				"Warning: not deleting missing records from collection %s because of import errors.\n",
+				collection.Name,
+			)
+			continue
+		}
+		if err := im.syncDelete(collection, keep[collection.Id], existing[collection.Id], im.maxDelete); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remapFiles prepares the id remapping of the files ahead of the import.
+// The records of the match_on collections are matched and, with newIds
+// set, all records get their new ids up front, so the relations to them
+// can be rewritten regardless of the import order. Stdin is remapped when
+// it is imported.
+func (im *recordsImporter) remapFiles(files []*recordsFile) error {
+	// load errors are reported by the import itself
+	report := im.report
+	im.report = newImportReport()
+	defer func() {
+		im.report = report
+	}()
+
+	for _, f := range files {
+		collection, err := im.app.FindCollectionByNameOrId(f.Collection)
+		if err != nil {
+			return err
+		}
+		if _, ok := im.matchOn[collection.Name]; (!ok && !im.newIds) || f.Path == "-" {
+			continue
+		}
+		records, err := im.decodeFile(collection, f.Path)
+		if err != nil {
+			continue // reported by the import itself
+		}
+		if err := im.remapRecords(collection, records); err != nil {
+			return err
+		}
+		im.forget(records)
+	}
+	return nil
+}
+
+// saveRecords saves the imported records to the collection, skipping the
+// nil records that failed to decode. If upsert is true, records that
+// already exist are updated instead of created. If the import has a report,
+// failed records are added to it instead of aborting the import.
+func (im *recordsImporter) saveRecords(
+	collection *core.Collection,
+	records []*core.Record,
+	source string,
+	upsert bool,
+) error {
+	defer im.forget(records)
+	if im.fast {
+		return im.insertRecords(collection, records, source, upsert)
+	}
+	for _, i := range saveOrder(collection, records) {
+		record := records[i]
+		if err := im.saveRecord(collection, record, upsert); err != nil {
+			if im.report == nil {
+				return importRecordError(source, i+1, record, err)
+			}
+			im.report.add(collection, source, i+1, record, im.sourceRows[record], err)
+		}
+	}
+	return nil
+}
+
+func (im *recordsImporter) saveRecord(collection *core.Collection, record *core.Record, upsert bool) error {
+	if im.hooks != hooksAll {
+		data, err := im.prepareInsert(im.app, record)
+		if err != nil {
+			return err
+		}
+		columns, touched := im.updateColumns(collection, record)
+		row := &fastRow{record: record, data: data, columns: columns, touched: touched}
+		return insertBatch(im.app, collection, []*fastRow{row}, upsert)
+	}
+	var autodates dbx.Params
+	if im.p.PreserveAutodate {
+		autodates = autodateValues(record)
+	}
+	if upsert && record.Id != "" {
+		existing, err := im.app.FindRecordById(collection, record.Id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if existing != nil {
+			columns, _ := im.updateColumns(collection, record)
+			for _, column := range columns {
+				existing.SetRaw(column, record.GetRaw(column))
+			}
+			record = existing
+		}
+	}
+	if err := im.prepareRecord(record); err != nil {
+		return err
+	}
+	save := im.app.SaveWithContext
+	if im.p.NoValidate {
+		save = im.app.SaveNoValidateWithContext
+	}
+	if err := save(im.ctx, record); err != nil {
+		return err
+	}
+	if len(autodates) > 0 {
+		// restore the values overwritten by the save, e.g. the updated
+		// value of an unchanged record or values changed by hooks
+		_, err := im.app.DB().Update(collection.Name, autodates, dbx.HashExp{"id": record.Id}).Execute()
+		return err
+	}
+	return nil
+}
+
+// updateColumns returns the fields to update when the record already
+// exists: the decoded columns of the record, or all fields if they are
+// unknown, except for the id, the credentials and, unless preserve_autodate
+// is set, the autodate fields. touched holds the other autodate fields with
+// onUpdate, which are set to the current time, as with a regular save.
+func (im *recordsImporter) updateColumns(collection *core.Collection, record *core.Record) (columns, touched []string) {
+	decoded, known := im.columns[record]
+	columns = []string{}
+	for _, field := range collection.Fields {
+		name := field.GetName()
+		autodate, isAutodate := field.(*core.AutodateField)
+		switch {
+		case name == core.FieldNameId, name == core.FieldNameTokenKey, field.Type() == core.FieldTypePassword:
+			continue // keep the existing id and credentials
+		case isAutodate && (!im.p.PreserveAutodate || known && !slices.Contains(decoded, name)):
+			if autodate.OnUpdate {
+				touched = append(touched, name)
+			}
+			continue
+		case known && !slices.Contains(decoded, name):
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns, touched
+}
+
+// forget drops the decoded columns and source rows of the records once
+// they are saved.
+func (im *recordsImporter) forget(records []*core.Record) {
+	for _, record := range records {
+		delete(im.columns, record)
+		delete(im.sourceRows, record)
+	}
+}
+
+// prepareRecord sets the id and credentials of new records and applies the
+// auth overrides.
+func (im *recordsImporter) prepareRecord(record *core.Record) error {
+	if record.IsNew() {
+		record.MarkAsNew()
+		if record.Id == "" {
+			id, err := newRecordId()
+			if err != nil {
+				return err
+			}
+			record.Id = id
+		}
+		if record.Collection().IsAuth() {
+			record.Set(core.FieldNamePassword, security.RandomString(30))
+			record.RefreshTokenKey()
+			if raw, ok := record.GetRaw(core.FieldNamePassword).(*core.PasswordFieldValue); ok {
+				raw.Plain = ""
+			}
+		}
+	}
+	if record.Collection().IsAuth() {
+		if verified, ok := im.p.OverrideVerified.GetValue(); ok {
+			record.SetVerified(verified)
+		}
+		if visibility, ok := im.p.OverrideEmailVisibility.GetValue(); ok {
+			record.SetEmailVisibility(visibility)
+		}
+	}
+	return nil
+}
+
+// loadAutodates sets the autodate values of the row that the decoder did not
+// load, since record.Load ignores autodate fields.
+func loadAutodates(record *core.Record, row map[string]any) error {
+	errs := validation.Errors{}
+	for _, field := range record.Collection().Fields {
+		if field.Type() != core.FieldTypeAutodate || !record.GetDateTime(field.GetName()).IsZero() {
+			continue
+		}
+		value, ok := row[field.GetName()]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		date, err := types.ParseDateTime(value)
+		if err != nil || date.IsZero() {
+			errs[field.GetName()] = validation.NewError("validation_invalid_date", "Invalid autodate value")
+			continue
+		}
+		record.SetRaw(field.GetName(), date)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// autodateValues returns the non-zero autodate values of the record.
+func autodateValues(record *core.Record) dbx.Params {
+	values := dbx.Params{}
+	for _, field := range record.Collection().Fields {
+		if field.Type() != core.FieldTypeAutodate {
+			continue
+		}
+		if date := record.GetDateTime(field.GetName()); !date.IsZero() {
+			values[field.GetName()] = date.String()
+		}
+	}
+	return values
+}
+
+// saveOrder returns the indexes of the non-nil records in the order to save
+// them, with the records that other records of the slice relate to through
+// a self relation first. Relation cycles are broken by the slice order.
+func saveOrder(collection *core.Collection, records []*core.Record) []int {
+	relations := selfRelations(collection)
+
+	order := make([]int, 0, len(records))
+	if len(relations) == 0 {
+		for i, record := range records {
+			if record != nil {
+				order = append(order, i)
+			}
+		}
+		return order
+	}
+
+	byId := make(map[string]int, len(records))
+	for i, record := range records {
+		if record != nil && record.Id != "" {
+			byId[record.Id] = i
+		}
+	}
+
+	visited := make([]bool, len(records))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		for _, relation := range relations {
+			for _, id := range records[i].GetStringSlice(relation.Name) {
+				if j, ok := byId[id]; ok {
+					visit(j)
+				}
+			}
+		}
+		order = append(order, i)
+	}
+	for i, record := range records {
+		if record != nil {
+			visit(i)
+		}
+	}
+
+	return order
+}
+
+// selfRelations returns the relation fields of the collection to itself.
+func selfRelations(collection *core.Collection) []*core.RelationField {
+	relations := []*core.RelationField{}
+	for _, field := range collection.Fields {
+		if relation, ok := field.(*core.RelationField); ok && relation.CollectionId == collection.Id {
+			relations = append(relations, relation)
+		}
+	}
+	return relations
+}
+
+// decodeRowsFile decodes the rows of the records data file at path.
+func decodeRowsFile(decoder RowsHandler, path string) ([]map[string]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decoder.DecodeRows(file)
+}
+
+// recordsSource returns the display name of the records file path.
+func recordsSource(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return path
+}
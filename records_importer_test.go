@@ -0,0 +1,197 @@
+package import_export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestSaveRecordsUpsert(t *testing.T) {
+	const (
+		oldDate  = "2020-01-01 00:00:00.000Z"
+		fileDate = "2021-01-01 00:00:00.000Z"
+	)
+
+	// expectedUpdated is empty if updated is expected to be set to the time
+	// of the import
+	scenarios := []struct {
+		name             string
+		hooks            string
+		fast             bool
+		preserveAutodate bool
+		file             string
+		expectedTitle    string
+		expectedCreated  string
+		expectedUpdated  string
+	}{
+		{"hooks all", hooksAll, false, false, `[{"id":"a00000000000001","title":"new"}]`, "new", oldDate, ""},
+		{"hooks model_only", hooksModelOnly, false, false, `[{"id":"a00000000000001","title":"new"}]`, "new", oldDate, ""},
+		{"hooks none", hooksNone, false, false, `[{"id":"a00000000000001","title":"new"}]`, "new", oldDate, ""},
+		{"fast", hooksModelOnly, true, false, `[{"id":"a00000000000001","title":"new"}]`, "new", oldDate, ""},
+		{"only id", hooksAll, false, false, `[{"id":"a00000000000001"}]`, "old", oldDate, ""},
+		{"fast only id", hooksModelOnly, true, false, `[{"id":"a00000000000001"}]`, "old", oldDate, ""},
+		{
+			"file autodates without preserve autodate",
+			hooksModelOnly,
+			true,
+			false,
+			`[{"id":"a00000000000001","title":"new","created":"` + fileDate + `","updated":"` + fileDate + `"}]`,
+			"new",
+			oldDate,
+			"",
+		},
+		{
+			"preserve autodate",
+			hooksAll,
+			false,
+			true,
+			`[{"id":"a00000000000001","title":"new","created":"` + fileDate + `","updated":"` + fileDate + `"}]`,
+			"new",
+			fileDate,
+			fileDate,
+		},
+		{
+			"preserve autodate without autodates",
+			hooksAll,
+			false,
+			true,
+			`[{"id":"a00000000000001","title":"new"}]`,
+			"new",
+			oldDate,
+			"",
+		},
+		{
+			"model_only preserve autodate",
+			hooksModelOnly,
+			false,
+			true,
+			`[{"id":"a00000000000001","title":"new","created":"` + fileDate + `","updated":"` + fileDate + `"}]`,
+			"new",
+			fileDate,
+			fileDate,
+		},
+		{
+			"fast preserve autodate",
+			hooksModelOnly,
+			true,
+			true,
+			`[{"id":"a00000000000001","title":"new","created":"` + fileDate + `","updated":"` + fileDate + `"}]`,
+			"new",
+			fileDate,
+			fileDate,
+		},
+		{
+			"fast preserve autodate without updated",
+			hooksModelOnly,
+			true,
+			true,
+			`[{"id":"a00000000000001","title":"new","created":"` + fileDate + `"}]`,
+			"new",
+			fileDate,
+			"",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			collection := saveTestCollection(t, app, "posts",
+				&core.TextField{Name: "title"},
+				&core.TextField{Name: "body"},
+			)
+
+			existing := core.NewRecord(collection)
+			existing.Id = "a00000000000001"
+			existing.Set("title", "old")
+			existing.Set("body", "keep")
+			if err := app.Save(existing); err != nil {
+				t.Fatal(err)
+			}
+			_, err := app.DB().Update(
+				collection.Name,
+				dbx.Params{"created": oldDate, "updated": oldDate},
+				dbx.HashExp{"id": existing.Id},
+			).Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			im := newTestImporter(t, app)
+			im.hooks, im.fast = s.hooks, s.fast
+			im.p.PreserveAutodate = s.preserveAutodate
+
+			path := writeTestFile(t, t.TempDir(), "posts.json", s.file)
+			records, err := im.decodeFile(collection, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := types.NowDateTime()
+			if err := im.saveRecords(collection, records, path, true); err != nil {
+				t.Fatal(err)
+			}
+
+			record, err := app.FindRecordById(collection, existing.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title := record.GetString("title"); title != s.expectedTitle {
+				t.Errorf("Expected title %q, got %q", s.expectedTitle, title)
+			}
+			if body := record.GetString("body"); body != "keep" {
+				t.Errorf("Expected body %q, got %q", "keep", body)
+			}
+			if created := record.GetDateTime("created").String(); created != s.expectedCreated {
+				t.Errorf("Expected created %q, got %q", s.expectedCreated, created)
+			}
+			updated := record.GetDateTime("updated")
+			if s.expectedUpdated != "" && updated.String() != s.expectedUpdated {
+				t.Errorf("Expected updated %q, got %q", s.expectedUpdated, updated)
+			}
+			if s.expectedUpdated == "" && updated.Time().Before(start.Time().Truncate(time.Millisecond)) {
+				t.Errorf("Expected updated to be set to the time of the import (%q), got %q", start, updated)
+			}
+			if len(im.columns) != 0 {
+				t.Errorf("Expected the decoded columns to be dropped, got %d", len(im.columns))
+			}
+		})
+	}
+}
+
+// recordsOnlyHandler hides the RowsHandler methods of a handler.
+type recordsOnlyHandler struct {
+	RecordsHandler
+}
+
+func TestDecodeFileWithoutRowsHandler(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		preserveAutodate bool
+		expectError      bool
+	}{
+		{"default", false, false},
+		{"preserve autodate", true, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			collection := saveTestCollection(t, app, "posts", &core.TextField{Name: "title"})
+			path := writeTestFile(t, t.TempDir(), "posts.json", `[{"id":"a00000000000001","title":"new"}]`)
+
+			im := newTestImporter(t, app)
+			im.decoder = recordsOnlyHandler{im.decoder}
+			im.p.PreserveAutodate = s.preserveAutodate
+
+			records, err := im.decodeFile(collection, path)
+			if hasErr := err != nil; hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v", s.expectError, err)
+			}
+			if !s.expectError && len(records) != 1 {
+				t.Fatalf("Expected 1 record, got %d", len(records))
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package import_export
+
+import (
+	"fmt"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/list"
+	"github.com/spf13/cast"
+)
+
+// Row key declaring a symbolic reference to the record of the row, which
+// relation values of any file of the import can refer to as
+// "@collection:ref".
+const refKey = "_ref"
+
+// recordRef is the record id a symbolic reference resolves to.
+type recordRef struct {
+	id string
+	// source is the file declaring the reference, or empty if the
+	// reference is used before the file declaring it is decoded.
+	source string
+	used   bool
+}
+
+// ref returns the reference of the collection, creating an undeclared one
+// with a new id if needed.
+func (im *recordsImporter) ref(collection *core.Collection, name string) (*recordRef, error) {
+	refs := im.refs[collection.Id]
+	if refs == nil {
+		refs = map[string]*recordRef{}
+		im.refs[collection.Id] = refs
+	}
+	if ref, ok := refs[name]; ok {
+		return ref, nil
+	}
+	id, err := newRecordId()
+	if err != nil {
+		return nil, err
+	}
+	ref := &recordRef{id: id}
+	refs[name] = ref
+	return ref, nil
+}
+
+// declareRefs declares the references of the rows of the source, before
+// any references are resolved, so rows can refer to rows of the same file.
+// Rows declaring a reference without an id get the id of the reference.
+// The result holds the error of each row, if any.
+func (im *recordsImporter) declareRefs(collection *core.Collection, source string, rows []map[string]any) ([]error, error) {
+	errs := make([]error, len(rows))
+	declared := map[string]bool{}
+
+	for i, row := range rows {
+		value, ok := row[refKey]
+		if !ok {
+			continue
+		}
+		delete(row, refKey)
+
+		name := cast.ToString(value)
+		if name == "" {
+			continue
+		}
+
+		ref, err := im.ref(collection, name)
+		if err != nil {
+			return nil, err
+		}
+
+		id := cast.ToString(row[core.FieldNameId])
+		switch {
+		case declared[name] || (ref.source != "" && ref.source != source):
+			errs[i] = refError(fmt.Sprintf("Duplicate reference %q", name))
+			continue
+		case id == "":
+			row[core.FieldNameId] = ref.id
+		case id == ref.id:
+		case ref.used:
+			errs[i] = refError(fmt.Sprintf("Reference %q is used before its declaration with an explicit id", name))
+			continue
+		default:
+			ref.id = id
+		}
+		declared[name] = true
+		ref.source = source
+	}
+
+	return errs, nil
+}
+
+// resolveRefs replaces the "@collection:ref" values of the relation fields
+// of the row with the ids of the referenced records. References that are
+// not declared by any row result in an error.
+func (im *recordsImporter) resolveRefs(collection *core.Collection, row map[string]any) error {
+	errs := validation.Errors{}
+
+	for column, value := range row {
+		relation, ok := collection.Fields.GetByName(column).(*core.RelationField)
+		if !ok {
+			continue
+		}
+
+		values := list.ToUniqueStringSlice(value)
+		if !containsRef(values) {
+			continue
+		}
+
+		for i, v := range values {
+			if !strings.HasPrefix(v, "@") {
+				continue
+			}
+			target, name, ok := strings.Cut(v[1:], ":")
+			if !ok || target == "" || name == "" {
+				errs[column] = validation.NewError(
+					"validation_invalid_ref",
+					fmt.Sprintf("Invalid reference %q, expected @collection:ref", v),
+				)
+				break
+			}
+			targetCollection, err := im.app.FindCachedCollectionByNameOrId(target)
+			if err != nil || targetCollection.Id != relation.CollectionId {
+				errs[column] = validation.NewError(
+					"validation_invalid_ref",
+					fmt.Sprintf("Reference %q is not a record of the related collection", v),
+				)
+				break
+			}
+			ref, err := im.ref(targetCollection, name)
+			if err != nil {
+				return err
+			}
+			ref.used = true
+			if ref.source == "" {
+				errs[column] = validation.NewError(
+					"validation_undeclared_ref",
+					fmt.Sprintf("Reference %q is not declared by any file of the import", v),
+				)
+				break
+			}
+			values[i] = ref.id
+		}
+
+		if relation.IsMultiple() {
+			row[column] = values
+		} else if len(values) > 0 {
+			row[column] = values[len(values)-1]
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func containsRef(values []string) bool {
+	for _, v := range values {
+		if strings.HasPrefix(v, "@") {
+			return true
+		}
+	}
+	return false
+}
+
+func refError(message string) error {
+	return validation.Errors{
+		refKey: validation.NewError("validation_invalid_ref", message),
+	}
+}
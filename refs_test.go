@@ -0,0 +1,139 @@
+package import_export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestImportRefs(t *testing.T) {
+	type file struct {
+		name    string
+		delta   string
+		content string
+	}
+
+	scenarios := []struct {
+		name          string
+		files         []file
+		noValidate    bool
+		expectedError string
+	}{
+		{
+			"declared in the same file",
+			[]file{
+				{"people.json", "", `[{"_ref":"alice","name":"Alice"},{"name":"Bob","friend":"@people:alice"}]`},
+			},
+			false,
+			"",
+		},
+		{
+			"declared with an explicit id",
+			[]file{
+				{"people.json", "", `[{"_ref":"alice","id":"alice0000000001","name":"Alice"},{"name":"Bob","friend":"@people:alice"}]`},
+			},
+			false,
+			"",
+		},
+		{
+			"used before the file declaring it",
+			[]file{
+				{"people.json", "", `[{"name":"Bob","friend":"@people:alice"}]`},
+				{"people.20260101T000000.delta.json", "20260101T000000", `[{"_ref":"alice","id":"alice0000000001","name":"Alice"}]`},
+			},
+			true,
+			"",
+		},
+		{
+			"undeclared",
+			[]file{
+				{"people.json", "", `[{"_ref":"alice","name":"Alice"},{"name":"Bob","friend":"@people:tpyo"}]`},
+			},
+			true,
+			`Reference "@people:tpyo" is not declared by any file of the import`,
+		},
+		{
+			"declared twice",
+			[]file{
+				{"people.json", "", `[{"_ref":"alice","name":"Alice"}]`},
+				{"people.20260101T000000.delta.json", "20260101T000000", `[{"_ref":"alice","name":"Alice"}]`},
+			},
+			false,
+			`Duplicate reference "alice"`,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app := newTestApp(t)
+			collection := saveTestCollection(t, app, "people", &core.TextField{Name: "name"})
+			collection.Fields.Add(&core.RelationField{Name: "friend", CollectionId: collection.Id, MaxSelect: 1})
+			if err := app.Save(collection); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			files := []*recordsFile{}
+			for _, f := range s.files {
+				files = append(files, &recordsFile{
+					Path:       writeTestFile(t, dir, f.name, f.content),
+					Collection: collection.Name,
+					Delta:      f.delta,
+				})
+			}
+
+			im := newTestImporter(t, app)
+			im.mode = importModeReplace
+			im.p.NoValidate = s.noValidate
+			if err := im.checkFiles(files); err != nil {
+				t.Fatal(err)
+			}
+			err := im.importFiles(files)
+			if s.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), s.expectedError) {
+					t.Fatalf("Expected error %q, got %v", s.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			alice, err := app.FindFirstRecordByData(collection, "name", "Alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			bob, err := app.FindFirstRecordByData(collection, "name", "Bob")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if friend := bob.GetString("friend"); friend != alice.Id {
+				t.Errorf("Expected Bob's friend to be Alice (%q), got %q", alice.Id, friend)
+			}
+		})
+	}
+}
+
+func TestDeclareRefsExplicitIdAfterUse(t *testing.T) {
+	app := newTestApp(t)
+	collection := saveTestCollection(t, app, "people", &core.TextField{Name: "name"})
+	collection.Fields.Add(&core.RelationField{Name: "friend", CollectionId: collection.Id, MaxSelect: 1})
+	if err := app.Save(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	im := newTestImporter(t, app)
+
+	// a use before the declaration fixes the id of the reference
+	if err := im.resolveRefs(collection, map[string]any{"friend": "@people:alice"}); err == nil {
+		t.Fatal("Expected an undeclared reference error, got nil")
+	}
+	errs, err := im.declareRefs(collection, "people.json", []map[string]any{{refKey: "alice", "id": "alice0000000001"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs[0] == nil || !strings.Contains(errs[0].Error(), "used before its declaration with an explicit id") {
+		t.Errorf("Expected an explicit id conflict, got %v", errs[0])
+	}
+}
@@ -0,0 +1,173 @@
+package import_export
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/spf13/cast"
+)
+
+// parseMatchOn parses the "collection:field" values of the match_on flag
+// into a map of natural key fields keyed by collection name. The fields
+// must have a unique index.
+func parseMatchOn(app core.App, values []string) (map[string]string, error) {
+	matchOn := map[string]string{}
+	for _, value := range values {
+		name, field, ok := strings.Cut(value, ":")
+		if !ok || name == "" || field == "" {
+			return nil, fmt.Errorf("invalid match_on %q, expected collection:field", value)
+		}
+		collection, err := app.FindCollectionByNameOrId(name)
+		if err != nil {
+			return nil, fmt.Errorf("match_on collection %s: %w", name, err)
+		}
+		if field == core.FieldNameId || collection.Fields.GetByName(field) == nil {
+			return nil, fmt.Errorf("match_on field %s is not a field of collection %s", field, collection.Name)
+		}
+		if !dbutils.HasSingleColumnUniqueIndex(field, collection.Indexes) {
+			return nil, fmt.Errorf("match_on field %s of collection %s has no unique index", field, collection.Name)
+		}
+		matchOn[collection.Name] = field
+	}
+	return matchOn, nil
+}
+
+// matchKeys returns the ids of the existing records of a match_on
+// collection keyed by their natural key, or nil if the collection has no
+// natural key.
+func (im *recordsImporter) matchKeys(collection *core.Collection) (map[string]string, error) {
+	field, ok := im.matchOn[collection.Name]
+	if !ok {
+		return nil, nil
+	}
+
+	if keys, ok := im.matchKeyCache[collection.Name]; ok {
+		return keys, nil
+	}
+
+	rows := []struct {
+		Id  string `db:"id"`
+		Key any    `db:"key"`
+	}{}
+	err := im.app.DB().
+		Select("[[id]]", fmt.Sprintf("[[%s]] AS [[key]]", field)).
+		From(collection.Name).
+		Where(dbx.Not(dbx.HashExp{field: ""})).
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]string, len(rows))
+	for _, row := range rows {
+		keys[cast.ToString(row.Key)] = row.Id
+	}
+	im.matchKeyCache[collection.Name] = keys
+
+	return keys, nil
+}
+
+// remapRecords replaces the ids of the records of a match_on collection
+// with the ids of the existing records with the same natural key and, if
+// newIds is set, the ids of all other records with new ids. The replaced
+// ids are remembered to rewrite the relations to them.
+func (im *recordsImporter) remapRecords(collection *core.Collection, records []*core.Record) error {
+	keys, err := im.matchKeys(collection)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		if keys != nil {
+			id, ok := keys[record.GetString(im.matchOn[collection.Name])]
+			if ok {
+				if record.Id != "" && record.Id != id {
+					im.setMappedId(collection.Id, record.Id, id)
+				}
+				record.Id = id
+				continue
+			}
+		}
+		if record.Id, err = im.newId(collection.Id, record.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newId returns the id that replaces the source id of an imported record of
+// the collection: the id it was matched to before or, with newIds set, a
+// new id, on first use, otherwise the source id.
+func (im *recordsImporter) newId(collectionId, id string) (string, error) {
+	if mapped, ok := im.idMap[collectionId][id]; ok {
+		return mapped, nil
+	}
+	if !im.newIds || id == "" {
+		return id, nil
+	}
+	mapped, err := newRecordId()
+	if err != nil {
+		return "", err
+	}
+	im.setMappedId(collectionId, id, mapped)
+	return mapped, nil
+}
+
+// mappedId returns the id that replaces the id a relation to the collection
+// refers to. Ids that no imported record replaces, e.g. of existing
+// records, are kept.
+func (im *recordsImporter) mappedId(collectionId, id string) string {
+	if mapped, ok := im.idMap[collectionId][id]; ok {
+		return mapped
+	}
+	return id
+}
+
+func (im *recordsImporter) setMappedId(collectionId, id, mapped string) {
+	if im.idMap[collectionId] == nil {
+		im.idMap[collectionId] = map[string]string{}
+	}
+	im.idMap[collectionId][id] = mapped
+}
+
+// rewriteRelations replaces the relation ids of the records with the ids
+// that replace them.
+func (im *recordsImporter) rewriteRelations(collection *core.Collection, records []*core.Record) error {
+	for _, field := range collection.Fields {
+		relation, ok := field.(*core.RelationField)
+		if !ok {
+			continue
+		}
+		if len(im.idMap[relation.CollectionId]) == 0 {
+			continue
+		}
+		for _, record := range records {
+			if record == nil {
+				continue
+			}
+			values := record.GetStringSlice(relation.Name)
+			for i, value := range values {
+				values[i] = im.mappedId(relation.CollectionId, value)
+			}
+			if relation.IsMultiple() {
+				record.Set(relation.Name, values)
+			} else if len(values) > 0 {
+				record.Set(relation.Name, values[0])
+			}
+		}
+	}
+	return nil
+}
+
+// newRecordId generates a random id in the default record id format.
+func newRecordId() (string, error) {
+	return security.RandomStringByRegex(`[a-z0-9]{15}`)
+}
@@ -0,0 +1,101 @@
+package import_export
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestMappedId(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		newIds           bool
+		id               string
+		expectedRemapped bool
+	}{
+		{"without new ids", false, "a00000000000001", false},
+		{"declared id", true, "a00000000000001", true},
+		{"undeclared id", true, "e00000000000001", false},
+		{"empty id", true, "", false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			im := &recordsImporter{newIds: s.newIds, idMap: map[string]map[string]string{}}
+
+			// the id declared by an imported record
+			declared, err := im.newId("posts", "a00000000000001")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again, _ := im.newId("posts", "a00000000000001"); again != declared {
+				t.Fatalf("Expected the same new id %q on reuse, got %q", declared, again)
+			}
+
+			mapped := im.mappedId("posts", s.id)
+			if remapped := mapped != s.id; remapped != s.expectedRemapped {
+				t.Fatalf("Expected remapped %v, got %q for %q", s.expectedRemapped, mapped, s.id)
+			}
+			if s.expectedRemapped && mapped != declared {
+				t.Errorf("Expected the declared new id %q, got %q", declared, mapped)
+			}
+		})
+	}
+}
+
+func TestNewIdsKeepRelationsToExistingRecords(t *testing.T) {
+	app := newTestApp(t)
+	users := saveTestCollection(t, app, "users_base", &core.TextField{Name: "name"})
+	posts := saveTestCollection(t, app, "posts",
+		&core.TextField{Name: "title"},
+		&core.RelationField{Name: "author", CollectionId: users.Id, MaxSelect: 1},
+	)
+
+	existing := core.NewRecord(users)
+	existing.Id = "e00000000000001"
+	existing.Set("name", "existing")
+	if err := app.Save(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "users_base.json", `[{"id":"u00000000000001","name":"new"}]`)
+	writeTestFile(t, dir, "posts.json", `[
+		{"id":"p00000000000001","title":"by new","author":"u00000000000001"},
+		{"id":"p00000000000002","title":"by existing","author":"e00000000000001"}
+	]`)
+	files, err := findRecordsFiles(dir, "json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, err = sortRecordsFilesByRelations(app, files); err != nil {
+		t.Fatal(err)
+	}
+
+	im := newTestImporter(t, app)
+	im.newIds, im.noDelete = true, true
+	if err := im.remapFiles(files); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.importFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	newUser, err := app.FindFirstRecordByData(users, "name", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newUser.Id == "u00000000000001" {
+		t.Error("Expected the imported user to get a new id")
+	}
+
+	for title, author := range map[string]string{"by new": newUser.Id, "by existing": existing.Id} {
+		post, err := app.FindFirstRecordByData(posts, "title", title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.GetString("author") != author {
+			t.Errorf("Expected post %q to relate to %q, got %q", title, author, post.GetString("author"))
+		}
+	}
+}
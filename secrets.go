@@ -0,0 +1,113 @@
+package import_export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
+)
+
+// Matches the ${VAR} environment variable references of collection files.
+var envReferenceRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// secretPlaceholder returns the environment variable reference written in
+// place of the client secret of the oauth2 provider of the collection, e.g.
+// ${USERS_GOOGLE_CLIENT_SECRET}.
+func secretPlaceholder(collection, provider string) string {
+	name := strings.ToUpper(nonAlphanumericRegex.ReplaceAllString(collection+"_"+provider, "_"))
+	return "${" + name + "_CLIENT_SECRET}"
+}
+
+// encodeCollection encodes the collection, with placeholders for the
+// client secrets of its oauth2 providers, if the oauth2 config is exported.
+// The collection model never serializes its secrets, so placeholders
+// require a CollectionDataHandler.
+func (p *Plugin) encodeCollection(encoder CollectionHandler, collection *core.Collection, writer io.Writer) error {
+	if !p.IncludeOauth2 || !collection.IsAuth() || len(collection.OAuth2.Providers) == 0 {
+		return encoder.EncodeCollection(collection, writer)
+	}
+
+	dataEncoder, ok := encoder.(CollectionDataHandler)
+	if !ok {
+		return fmt.Errorf("the %s handler does not support oauth2 secret placeholders", encoder.FileExtension())
+	}
+
+	raw, err := json.Marshal(collection)
+	if err != nil {
+		return err
+	}
+	data := map[string]any{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+
+	oauth2, _ := data["oauth2"].(map[string]any)
+	for _, value := range cast.ToSlice(oauth2["providers"]) {
+		if provider, ok := value.(map[string]any); ok {
+			provider["clientSecret"] = secretPlaceholder(collection.Name, cast.ToString(provider["name"]))
+		}
+	}
+
+	return dataEncoder.EncodeCollectionData(data, writer)
+}
+
+// expandEnv replaces the ${VAR} references in the string values of the
+// oauth2 providers of the decoded collections with the values of the
+// environment variables. Other values, such as rules or view queries, are
+// left untouched. References to unset variables result in an error listing
+// all of them.
+func expandEnv(collections []map[string]any) error {
+	missing := []string{}
+	for _, collection := range collections {
+		oauth2, ok := collection["oauth2"].(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, provider := range cast.ToSlice(oauth2["providers"]) {
+			expandEnvValue(provider, &missing)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf(
+			"environment variable(s) referenced by the collection files are not set: %s",
+			strings.Join(slices.Compact(missing), ", "),
+		)
+	}
+	return nil
+}
+
+func expandEnvValue(value any, missing *[]string) any {
+	switch value := value.(type) {
+	case string:
+		return envReferenceRegex.ReplaceAllStringFunc(value, func(reference string) string {
+			name := envReferenceRegex.FindStringSubmatch(reference)[1]
+			env, ok := os.LookupEnv(name)
+			if !ok {
+				*missing = append(*missing, name)
+			}
+			return env
+		})
+	case map[string]any:
+		for key, v := range value {
+			value[key] = expandEnvValue(v, missing)
+		}
+	case []any:
+		for i, v := range value {
+			value[i] = expandEnvValue(v, missing)
+		}
+	case []map[string]any:
+		for _, v := range value {
+			expandEnvValue(v, missing)
+		}
+	}
+	return value
+}
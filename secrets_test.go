@@ -0,0 +1,75 @@
+package import_export
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("TEST_CLIENT_ID", "id123")
+	t.Setenv("TEST_CLIENT_SECRET", "secret123")
+
+	scenarios := []struct {
+		name          string
+		collection    map[string]any
+		expected      map[string]any
+		expectedError bool
+	}{
+		{
+			"provider settings",
+			map[string]any{"oauth2": map[string]any{"providers": []any{
+				map[string]any{"name": "google", "clientId": "${TEST_CLIENT_ID}", "clientSecret": "${TEST_CLIENT_SECRET}"},
+			}}},
+			map[string]any{"oauth2": map[string]any{"providers": []any{
+				map[string]any{"name": "google", "clientId": "id123", "clientSecret": "secret123"},
+			}}},
+			false,
+		},
+		{
+			"provider settings as map slice",
+			map[string]any{"oauth2": map[string]any{"providers": []map[string]any{
+				{"name": "google", "clientSecret": "prefix-${TEST_CLIENT_SECRET}"},
+			}}},
+			map[string]any{"oauth2": map[string]any{"providers": []map[string]any{
+				{"name": "google", "clientSecret": "prefix-secret123"},
+			}}},
+			false,
+		},
+		{
+			"rules and view queries",
+			map[string]any{
+				"listRule":  "note = '${TEST_CLIENT_SECRET}'",
+				"viewQuery": "SELECT '${TEST_CLIENT_ID}' as id",
+				"oauth2":    map[string]any{"enabled": "${TEST_CLIENT_ID}"},
+			},
+			map[string]any{
+				"listRule":  "note = '${TEST_CLIENT_SECRET}'",
+				"viewQuery": "SELECT '${TEST_CLIENT_ID}' as id",
+				"oauth2":    map[string]any{"enabled": "${TEST_CLIENT_ID}"},
+			},
+			false,
+		},
+		{
+			"unset variable",
+			map[string]any{"oauth2": map[string]any{"providers": []any{
+				map[string]any{"name": "google", "clientSecret": "${TEST_UNSET_SECRET}"},
+			}}},
+			map[string]any{"oauth2": map[string]any{"providers": []any{
+				map[string]any{"name": "google", "clientSecret": ""},
+			}}},
+			true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			err := expandEnv([]map[string]any{s.collection})
+			if hasErr := err != nil; hasErr != s.expectedError {
+				t.Fatalf("Expected hasErr %v, got %v", s.expectedError, err)
+			}
+			if !reflect.DeepEqual(s.collection, s.expected) {
+				t.Errorf("Expected %v, got %v", s.expected, s.collection)
+			}
+		})
+	}
+}